@@ -5,16 +5,20 @@
 package main
 
 import (
+	"bufio"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/goki/ki/dirs"
 	"github.com/goki/pi/filecat"
-	_ "github.com/goki/pi/langs"
+	"github.com/goki/pi/lex"
+	"github.com/goki/pi/parse"
 	"github.com/goki/pi/pi"
+	_ "github.com/goki/pi/suplangs"
 	"github.com/goki/pi/syms"
 )
 
@@ -24,6 +28,12 @@ func main() {
 	var path string
 	var recurse bool
 	var excl string
+	var astFmt string
+	var memo bool
+	var profile bool
+	var pprof string
+	var grammar bool
+	var tokens string
 
 	pi.LangSupport.OpenStd()
 
@@ -37,6 +47,12 @@ func main() {
 	flag.StringVar(&path, "path", "", "path to open -- can be to a directory or a filename within the directory -- or just last arg without a flag")
 	flag.BoolVar(&recurse, "r", false, "recursive -- apply to subdirectories")
 	flag.StringVar(&excl, "ex", "", "comma-separated list of directory names to exclude, for recursive case")
+	flag.BoolVar(&memo, "memo", false, "use the packrat memo table for parsing, and report its stats along with the total time")
+	flag.BoolVar(&profile, "prof", false, "profile the matching of each parser rule, and report a table of the rules taking the most time")
+	flag.StringVar(&pprof, "pprof", "", "profile the matching of each parser rule, and write the profile to this file in pprof format, for: go tool pprof -http=: file")
+	flag.BoolVar(&grammar, "grammar", false, "analyze the grammar given by path: a .pi or .pig grammar file, or a file in a supported language, and report any issues in it, instead of processing the directory")
+	flag.StringVar(&astFmt, "ast", "", "json or sexp -- parse the file given by path and write its Ast to stdout in this format, instead of processing the directory")
+	flag.StringVar(&tokens, "tokens", "", "language name -- stream the file given by path (or stdin if path is -) through the lexer for this language, and write its tokens to stdout one per line, instead of processing the directory -- works for files of any size")
 	flag.Parse()
 	if path == "" {
 		if flag.NArg() > 0 {
@@ -47,12 +63,57 @@ func main() {
 	}
 	Excludes = strings.Split(excl, ",")
 
+	if grammar {
+		nis, err := DoGrammar(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if nis > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if tokens != "" {
+		if err := DoTokens(path, tokens); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if astFmt != "" {
+		if err := DoAst(path, astFmt); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// todo: assuming go for now
+	lp, _ := pi.LangSupport.Props(filecat.Go)
+	lp.Lang.Parser().Memo = memo
+	lp.Lang.Parser().Profile = profile || pprof != ""
+	stt := time.Now()
 	if recurse {
 		DoGoRecursive(path)
 	} else {
 		DoGoPath(path)
 	}
+	fmt.Printf("Total time: %v\n", time.Since(stt))
+	if memo {
+		fmt.Printf("Memo: %v\n", parse.MemoTotal)
+	}
+	if profile {
+		parse.ProfileTotal.WriteTable(os.Stdout, 50)
+	}
+	if pprof != "" {
+		if err := WritePprof(pprof); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
 }
 
 func DoGoPath(path string) {
@@ -67,6 +128,116 @@ func DoGoPath(path string) {
 	}
 }
 
+// WritePprof writes the parse.ProfileTotal to given file in pprof format
+func WritePprof(fname string) error {
+	f, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	err = parse.ProfileTotal.WritePprof(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// DoAst parses the file at given path with the parser for its language,
+// and writes the Ast to stdout in given format: json or sexp
+func DoAst(path, astFmt string) error {
+	lp, err := pi.LangSupport.Props(filecat.SupportedFromFile(path))
+	if err != nil {
+		return err
+	}
+	pr := lp.Lang.Parser()
+	if pr == nil {
+		return fmt.Errorf("no parser for file: %v", path)
+	}
+	fs := pi.NewFileState()
+	if err := fs.Src.OpenFile(path); err != nil {
+		return err
+	}
+	pr.LexAll(fs)
+	pr.ParseAll(fs)
+	switch astFmt {
+	case "json":
+		return fs.Ast.WriteAstJSON(os.Stdout, true)
+	case "sexp":
+		return fs.Ast.WriteSexp(os.Stdout)
+	}
+	return fmt.Errorf("unknown Ast format: %v -- must be json or sexp", astFmt)
+}
+
+// DoTokens streams the file at given path, or stdin if path is -, through
+// the lexer for given language, and writes each token to stdout as
+// line:start-end token text, and any lexing errors to stderr as they occur
+func DoTokens(path, lang string) error {
+	lp, err := pi.LangSupport.PropsByName(lang)
+	if err != nil {
+		return err
+	}
+	pr := lp.Lang.Parser()
+	if pr == nil {
+		return fmt.Errorf("no lexer for language: %v", lang)
+	}
+	in := os.Stdin
+	if path != "-" {
+		if in, err = os.Open(path); err != nil {
+			return err
+		}
+		defer in.Close()
+	}
+	out := bufio.NewWriter(os.Stdout)
+	sc := lex.NewScanner(&pr.Lexer, in, path)
+	sc.ErrFunc = func(e *lex.Error) {
+		out.Flush() // keep errors in order with the tokens
+		fmt.Fprintln(os.Stderr, e.Report("", true, false))
+	}
+	err = sc.Scan(func(ln int, src []rune, lx lex.Lex) bool {
+		fmt.Fprintf(out, "%d:%d-%d\t%v\t%s\n", ln+1, lx.St, lx.Ed, lx.Tok, string(src[lx.St:lx.Ed]))
+		return true
+	})
+	if ferr := out.Flush(); err == nil {
+		err = ferr
+	}
+	if sc.NErrs > 0 && err == nil {
+		err = fmt.Errorf("%d lexing errors", sc.NErrs)
+	}
+	return err
+}
+
+// DoGrammar analyzes the grammar given by path: a .pi or .pig grammar
+// file, or a file in a supported language, and prints any issues found
+// in the grammar.  Returns the number of issues.
+func DoGrammar(path string) (int, error) {
+	var pr *pi.Parser
+	switch filepath.Ext(path) {
+	case ".pi", ".pig":
+		pr = pi.NewParser()
+		var err error
+		if filepath.Ext(path) == ".pi" {
+			err = pr.OpenJSON(path)
+		} else {
+			err = pr.OpenGrammar(path)
+		}
+		if err != nil {
+			return 0, err
+		}
+		pr.InitAll()
+	default:
+		lp, err := pi.LangSupport.Props(filecat.SupportedFromFile(path))
+		if err != nil {
+			return 0, err
+		}
+		if pr = lp.Lang.Parser(); pr == nil {
+			return 0, fmt.Errorf("no parser for file: %v", path)
+		}
+	}
+	for _, gi := range pr.GrammarIssues {
+		fmt.Println(gi)
+	}
+	return len(pr.GrammarIssues), nil
+}
+
 func DoGoRecursive(path string) {
 	DoGoPath(path)
 	drs := dirs.Dirs(path)
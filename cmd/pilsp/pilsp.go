@@ -0,0 +1,48 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// pilsp is a Language Server Protocol server for all of the languages
+// supported by GoPi, communicating over stdin / stdout.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/goki/pi/lsp"
+	"github.com/goki/pi/pi"
+	_ "github.com/goki/pi/suplangs"
+)
+
+func main() {
+	var trace bool
+	var logf string
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprintf(flag.CommandLine.Output(), "\nruns a language server on stdin / stdout -- configure your editor to launch it for Go, Markdown and TeX files\n\n")
+	}
+
+	flag.BoolVar(&trace, "trace", false, "log all messages received and sent")
+	flag.StringVar(&logf, "log", "", "file to write log messages to -- stderr by default")
+	flag.Parse()
+
+	if logf != "" {
+		f, err := os.Create(logf)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		log.SetOutput(f)
+	}
+
+	pi.LangSupport.OpenStd()
+
+	sv := lsp.NewServer(os.Stdin, os.Stdout)
+	sv.Trace = trace
+	os.Exit(sv.Run())
+}
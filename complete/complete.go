@@ -74,6 +74,39 @@ func (lk *Lookup) SetFile(fname string, st, ed int) {
 	lk.EdLine = ed
 }
 
+// Signature is used for returning the signature of a function being called,
+// for help while typing its arguments
+type Signature struct {
+
+	// name of the function
+	Name string `desc:"name of the function"`
+
+	// parameters of the function, as name and type (not including any method receiver)
+	Params []string `desc:"parameters of the function, as name and type (not including any method receiver)"`
+
+	// return values of the function
+	Returns []string `desc:"return values of the function"`
+
+	// index of the parameter currently being typed
+	ActiveParam int `desc:"index of the parameter currently being typed"`
+
+	// documentation for the function, if available
+	Desc string `desc:"documentation for the function, if available"`
+}
+
+// Label returns the signature as Name(params) returns
+func (sg *Signature) Label() string {
+	lbl := sg.Name + "(" + strings.Join(sg.Params, ", ") + ")"
+	switch len(sg.Returns) {
+	case 0:
+	case 1:
+		lbl += " " + sg.Returns[0]
+	default:
+		lbl += " (" + strings.Join(sg.Returns, ", ") + ")"
+	}
+	return lbl
+}
+
 // Edit is returned from completion edit function
 // to incorporate the selected completion
 type Edit struct {
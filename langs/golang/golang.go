@@ -18,6 +18,7 @@ import (
 	"github.com/goki/pi/langs"
 	"github.com/goki/pi/lex"
 	"github.com/goki/pi/pi"
+	"github.com/goki/pi/syms"
 	"github.com/goki/pi/token"
 )
 
@@ -67,6 +68,12 @@ func (gl *GoLang) ParseFile(fss *pi.FileStates, txt []byte) {
 	pr.ParseAll(pfs)
 	// pprf.End()
 	fss.EndProc() // only symbols still need locking, done separately
+	gl.FileSyms(fss, pfs)
+}
+
+// FileSyms adds the package symbols of a newly parsed file, along with
+// those from the rest of its directory and its imports, in the background
+func (gl *GoLang) FileSyms(fss *pi.FileStates, pfs *pi.FileState) {
 	path, _ := filepath.Split(pfs.Src.Filename)
 	if len(pfs.ParseState.Scopes) > 0 { // should be for complete files, not for snippets
 		pkg := pfs.ParseState.Scopes[0]
@@ -83,6 +90,60 @@ func (gl *GoLang) ParseFile(fss *pi.FileStates, txt []byte) {
 	}
 }
 
+// ReparseLines updates the Done state in place for an edit of given lines,
+// re-parsing only the edited region when possible.  If the imports changed,
+// they are added again, and otherwise just the types are resolved again.
+// Falls back on ParseFile if there is no prior parse of the file.
+func (gl *GoLang) ReparseLines(fss *pi.FileStates, txt []byte, st, nold, nnew int) {
+	pr := gl.Parser()
+	if pr == nil {
+		log.Println("ReparseLines: no parser -- must call pi.LangSupport.OpenStd() at startup!")
+		return
+	}
+	fss.ProcMu.Lock()
+	fs := fss.DoneNoLock() // only switched under ProcMu
+	lns := lex.RunesFromBytes(txt)
+	if len(fs.ParseState.Scopes) == 0 || len(fs.Src.Lexs) != len(lns)-nnew+nold {
+		fss.ProcMu.Unlock()
+		gl.ParseFile(fss, txt)
+		return
+	}
+	pkg := fs.ParseState.Scopes[0]
+	imps := gl.ImportNames(fs, pkg)
+	fs.Src.Lines = lns
+	incr := pr.ReparseLines(fs, st, nold, nnew)
+	fss.ProcMu.Unlock()
+	if !incr {
+		gl.FileSyms(fss, fs)
+		return
+	}
+	nimps := gl.ImportNames(fs, pkg)
+	if len(nimps) != len(imps) {
+		go gl.AddImportsToExts(fss, fs, pkg)
+		return
+	}
+	for nm := range nimps {
+		if !imps[nm] {
+			go gl.AddImportsToExts(fss, fs, pkg)
+			return
+		}
+	}
+	go gl.ResolveTypes(fs, pkg, true)
+}
+
+// ImportNames returns the names of the imports in given package symbol
+func (gl *GoLang) ImportNames(fs *pi.FileState, pkg *syms.Symbol) map[string]bool {
+	var imps syms.SymMap
+	fs.SymsMu.RLock()
+	pkg.Children.FindKindScoped(token.NameLibrary, &imps)
+	fs.SymsMu.RUnlock()
+	nms := make(map[string]bool, len(imps))
+	for nm := range imps {
+		nms[nm] = true
+	}
+	return nms
+}
+
 func (gl *GoLang) LexLine(fs *pi.FileState, line int, txt []rune) lex.Line {
 	pr := gl.Parser()
 	if pr == nil {
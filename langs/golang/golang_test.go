@@ -5,15 +5,27 @@
 package golang
 
 import (
+	"bytes"
+	"compress/gzip"
+	"flag"
 	"fmt"
 	"go/parser"
 	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/goki/ki/ki"
 	"github.com/goki/pi/filecat"
+	_ "github.com/goki/pi/langs/markdown"
 	"github.com/goki/pi/lex"
+	"github.com/goki/pi/parse"
+	"github.com/goki/pi/parse/parsetest"
 	"github.com/goki/pi/pi"
+	"github.com/goki/pi/syms"
 	"github.com/goki/prof"
 )
 
@@ -43,6 +55,158 @@ func TestParse(t *testing.T) {
 	prof.Profiling = false
 }
 
+// update writes the parsetest golden files from the current output
+var update = flag.Bool("update", false, "update parsetest golden files with the current parser output")
+
+// TestGrammar checks the parser output for the snippets in testdata/parse
+// against their golden files -- run with -update to rewrite them
+func TestGrammar(t *testing.T) {
+	lp, _ := pi.LangSupport.Props(filecat.Go)
+	parsetest.Dir(t, lp.Lang.Parser(), "testdata/parse", filecat.Go, *update, ".go")
+}
+
+// TestRecover parses with error recovery on, which is off by default,
+// on its own parser, checking the golden file and that the symbols
+// after the errors are still found
+func TestRecover(t *testing.T) {
+	pr := newParser(t)
+	pr.Recover = true
+	parsetest.Check(t, pr, "testdata/recover/recover.go", filecat.Go, *update)
+	fs := pi.NewFileState()
+	if err := fs.Src.OpenFile("testdata/recover/recover.go"); err != nil {
+		t.Fatal(err)
+	}
+	pr.LexAll(fs)
+	pr.ParseAll(fs)
+	if !fs.ParseHasErrs() {
+		t.Errorf("expected parse errors")
+	}
+	pkg, has := fs.ParseState.Syms["recover"]
+	if !has {
+		t.Fatalf("package symbol not found")
+	}
+	for _, fn := range [][]string{{"f", "y"}, {"g", "v"}} {
+		sy, has := pkg.Children[fn[0]]
+		if !has {
+			t.Errorf("function %v not found", fn[0])
+			continue
+		}
+		if _, has := sy.Children[fn[1]]; !has {
+			t.Errorf("symbol %v after error not found in %v", fn[1], fn[0])
+		}
+	}
+}
+
+// newParser returns a new Go parser loaded from go.pi, for tests that change
+// its options, as the shared one is also used for background parsing of
+// imports started by other tests
+func newParser(t testing.TB) *pi.Parser {
+	pr := pi.NewParser()
+	if err := pr.OpenJSON("go.pi"); err != nil {
+		t.Fatal(err)
+	}
+	pr.InitAll()
+	return pr
+}
+
+// memoParse parses the file, or src if not empty, with the memo table on or off,
+// returning the parsetest Output: the Ast, the tokens as retagged by the
+// ChgToken actions of the parser, and any errors
+func memoParse(pr *pi.Parser, fname, src string, memo bool) string {
+	pr.Memo = memo
+	b := []byte(src)
+	if src == "" {
+		b, _ = os.ReadFile(fname)
+	}
+	return parsetest.Output(pr, b, filepath.Base(fname), filecat.Go)
+}
+
+func TestMemo(t *testing.T) {
+	pr := newParser(t)
+	files, _ := filepath.Glob("testdata/parse/*.go")
+	srcs := map[string]string{
+		"sum.go":  "package p\n\nvar x = a" + strings.Repeat(" + a", 200) + "\n",
+		"call.go": "package p\n\nvar x = " + strings.Repeat("f(", 200) + "1" + strings.Repeat(")", 200) + "\n",
+		"sel.go":  "package p\n\nvar x = a" + strings.Repeat(".b(1)", 200) + "\n",
+	}
+	for _, fn := range files {
+		srcs[fn] = ""
+	}
+	srcs["testdata/textview.go"] = "" // large real file
+	for fn, src := range srcs {
+		got, want := memoParse(pr, fn, src, true), memoParse(pr, fn, src, false)
+		if got == want {
+			continue
+		}
+		gl, wl := strings.Split(got, "\n"), strings.Split(want, "\n")
+		for i := 0; i < len(gl) && i < len(wl); i++ {
+			if gl[i] != wl[i] {
+				t.Errorf("%v: output with memo differs from without at line %d:\n got: %v\nwant: %v", fn, i+1, gl[i], wl[i])
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkParseMemo(b *testing.B) {
+	pr := newParser(b)
+	for _, memo := range []bool{false, true} {
+		b.Run(fmt.Sprintf("Memo=%v", memo), func(b *testing.B) {
+			pr.Memo = memo
+			for i := 0; i < b.N; i++ {
+				fs := pi.NewFileState()
+				fs.Src.OpenFile("testdata/textview.go")
+				pr.LexAll(fs)
+				pr.ParseAll(fs)
+			}
+		})
+	}
+}
+
+func TestProfile(t *testing.T) {
+	pr := newParser(t)
+	pr.Profile = true
+	fs := pi.NewFileState()
+	fs.Src.OpenFile("testdata/parse/exprs.go")
+	pr.LexAll(fs)
+	pr.ParseAll(fs)
+	pp := &fs.ParseState.Profile
+	rps := pp.Sorted()
+	if len(rps) == 0 {
+		t.Fatal("no rules in profile")
+	}
+	atts, cached := 0, 0
+	for i, rp := range rps {
+		if rp.Matches > rp.Attempts || rp.Excluded > rp.Excludes || rp.Self > rp.Time {
+			t.Errorf("%v: inconsistent counts: %+v", rp.Rule.Name(), *rp)
+		}
+		if i > 0 && rp.Self > rps[i-1].Self {
+			t.Errorf("%v: not sorted by self time", rp.Rule.Name())
+		}
+		atts += rp.Attempts
+		cached += rp.Cached
+	}
+	if cached == 0 || cached >= atts {
+		t.Errorf("cached: %d out of attempts: %d", cached, atts)
+	}
+	var tb bytes.Buffer
+	pp.WriteTable(&tb, 10)
+	if n := strings.Count(tb.String(), "\n"); n != 11 {
+		t.Errorf("profile table has %d lines, want 11:\n%v", n, tb.String())
+	}
+	var pb bytes.Buffer
+	if err := pp.WritePprof(&pb); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := gzip.NewReader(&pb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := io.ReadAll(zr); err != nil || !bytes.Contains(b, []byte(rps[0].Rule.Name())) {
+		t.Errorf("pprof profile does not contain rule: %v: %v", rps[0].Rule.Name(), err)
+	}
+}
+
 // Note: couldn't get benchmark to do anything reasonable on this one, so just
 // using plain test on single iter
 
@@ -66,3 +230,336 @@ func TestGoParse(t *testing.T) {
 	// 	fmt.Println(fn.Name.Name)
 	// }
 }
+
+func TestFindReferences(t *testing.T) {
+	lp, _ := pi.LangSupport.Props(filecat.Go)
+	fs := pi.NewFileStates("testdata/refs/main.go", "", filecat.Go)
+	txt, err := lex.OpenFileBytes(fs.Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lp.Lang.ParseFile(fs, txt)
+	refs := func(ln, ch int) string {
+		var rs []string
+		for _, fr := range lp.Lang.FindReferences(fs, lex.Pos{Ln: ln, Ch: ch}) {
+			rs = append(rs, filepath.Base(fr.Filename)+":"+fr.Reg.St.String())
+		}
+		return strings.Join(rs, " ")
+	}
+	tests := []struct {
+		ln, ch int
+		want   string
+	}{
+		{2, 6, "main.go:3:5 main.go:7:9 main.go:12:7 other.go:4:7"}, // type Foo
+		{3, 2, "main.go:4:1 main.go:8:10 main.go:14:8"},             // field Bar
+		{12, 4, "main.go:7:14 main.go:13:3 other.go:5:10"},          // method Get via f.Get()
+		{11, 1, "main.go:12:1 main.go:13:1 main.go:14:6"},           // local f
+		{14, 2, "main.go:15:1 other.go:3:5"},                        // Helper from other file
+		{6, 6, "main.go:7:6 main.go:8:8"},                           // receiver f
+		{10, 6, "main.go:11:5"},                                     // main, not package main
+	}
+	for _, tt := range tests {
+		if got := refs(tt.ln, tt.ch); got != tt.want {
+			t.Errorf("refs at %d:%d: got %q want %q", tt.ln+1, tt.ch, got, tt.want)
+		}
+	}
+
+	// unsaved changes in an open other.go are used instead of the file on disk
+	ofn, _ := filepath.Abs("testdata/refs/other.go")
+	fs.OpenSrc = func(fname string) ([][]rune, bool) {
+		if fname != ofn {
+			return nil, false
+		}
+		return lex.RunesFromString("package main\n\nvar g Foo\n\nfunc Helper(y int) int {\n\tvar f Foo\n\treturn f.Get() + y\n}\n"), true
+	}
+	if got, want := refs(2, 6), "main.go:3:5 main.go:7:9 main.go:12:7 other.go:3:6 other.go:6:7"; got != want {
+		t.Errorf("refs with open other.go: got %q want %q", got, want)
+	}
+}
+
+func TestRename(t *testing.T) {
+	lp, _ := pi.LangSupport.Props(filecat.Go)
+	rn := lp.Lang.(pi.Renamer)
+	fs := pi.NewFileStates("testdata/refs/main.go", "", filecat.Go)
+	txt, err := lex.OpenFileBytes(fs.Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lp.Lang.ParseFile(fs, txt)
+	eds, err := rn.Rename(fs, lex.Pos{Ln: 2, Ch: 6}, "Baz")
+	if err != nil || len(eds) != 4 || eds[0].Text != "Baz" {
+		t.Errorf("rename Foo: %v %v", eds, err)
+	}
+	clashes := []struct {
+		ln, ch int
+		nm     string
+	}{
+		{11, 1, "x"},      // local f to local x
+		{3, 2, "Get"},     // field Bar to method Get
+		{14, 2, "Foo"},    // func Helper to type Foo
+		{13, 1, "Helper"}, // local x would shadow Helper call
+		{2, 6, "f x"},     // not an identifier
+	}
+	for _, tt := range clashes {
+		if eds, err := rn.Rename(fs, lex.Pos{Ln: tt.ln, Ch: tt.ch}, tt.nm); err == nil {
+			t.Errorf("rename at %d:%d to %v: expected error, got: %v", tt.ln+1, tt.ch, tt.nm, eds)
+		}
+	}
+	if _, err := rn.PrepareRename(fs, lex.Pos{Ln: 0, Ch: 1}); err == nil {
+		t.Errorf("prepare rename of package keyword: expected error")
+	}
+}
+
+func TestSignatureHelp(t *testing.T) {
+	lp, _ := pi.LangSupport.Props(filecat.Go)
+	sh := lp.Lang.(pi.SignatureHelper)
+	fss := pi.NewFileStates("testdata/sig/sig.go", "", filecat.Go)
+	txt, err := lex.OpenFileBytes(fss.Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lp.Lang.ParseFile(fss, txt)
+	fs := fss.Done()
+	fs.WaitGp.Wait()
+	TheGoLang.ResolveTypes(fs, fs.ParseState.Scopes[0], true) // no imports, so not done by ParseFile
+
+	pos := lex.Pos{Ln: 16, Ch: 1}
+	tests := []struct {
+		str   string
+		label string
+		arg   int
+	}{
+		{"Join(", "Join(elems []string, sep string) string", 0},
+		{`Join([]string{"a", "b"}, `, "Join(elems []string, sep string) string", 1},
+		{"b.Write(nil, ", "Write(p []byte, n int) (int, error)", 1},
+		{"(&b).Write(", "Write(p []byte, n int) (int, error)", 0},
+		{"b.Write(nil, Join(", "Join(elems []string, sep string) string", 0},
+		{`Join(nil, "(", `, "Join(elems []string, sep string) string", 2},
+	}
+	for _, tt := range tests {
+		sg, ok := sh.SignatureHelp(fss, tt.str, pos)
+		if !ok || sg.Label() != tt.label || sg.ActiveParam != tt.arg {
+			t.Errorf("signature for %q: got %v %q arg %d, want %q arg %d", tt.str, ok, sg.Label(), sg.ActiveParam, tt.label, tt.arg)
+		}
+	}
+	for _, str := range []string{"b.Write(nil)", "x := (", "nothere("} {
+		if sg, ok := sh.SignatureHelp(fss, str, pos); ok {
+			t.Errorf("signature for %q: expected none, got %q", str, sg.Label())
+		}
+	}
+}
+
+func TestFoldingRanges(t *testing.T) {
+	src := `package x
+
+// Doc comment
+// second line
+import (
+	"fmt"
+	"os"
+)
+
+/* block
+   comment */
+func f() {
+	fmt.Println([]string{
+		"a",
+	}, os.Args[0])
+}
+`
+	lp, _ := pi.LangSupport.Props(filecat.Go)
+	fss := pi.NewFileStates("fold.go", "", filecat.Go)
+	lp.Lang.ParseFile(fss, []byte(src))
+	frs := pi.LangFoldingRanges(lp.Lang, fss.Done())
+	want := []pi.FoldRange{
+		{Reg: lex.Reg{St: lex.Pos{Ln: 2, Ch: 0}, Ed: lex.Pos{Ln: 3, Ch: 14}}, Kind: pi.FoldComment},
+		{Reg: lex.Reg{St: lex.Pos{Ln: 4, Ch: 0}, Ed: lex.Pos{Ln: 7, Ch: 1}}, Kind: pi.FoldImports},
+		{Reg: lex.Reg{St: lex.Pos{Ln: 9, Ch: 0}, Ed: lex.Pos{Ln: 10, Ch: 13}}, Kind: pi.FoldComment},
+		{Reg: lex.Reg{St: lex.Pos{Ln: 11, Ch: 10}, Ed: lex.Pos{Ln: 15, Ch: 0}}, Kind: pi.FoldRegion},
+		{Reg: lex.Reg{St: lex.Pos{Ln: 12, Ch: 13}, Ed: lex.Pos{Ln: 14, Ch: 14}}, Kind: pi.FoldRegion},
+	}
+	if len(frs) != len(want) {
+		t.Fatalf("folding ranges: got %v, want %v", frs, want)
+	}
+	for i := range want {
+		if frs[i] != want[i] {
+			t.Errorf("folding range %d: got %v %v, want %v %v", i, frs[i].Reg, frs[i].Kind, want[i].Reg, want[i].Kind)
+		}
+	}
+}
+
+// reparseDump returns the Ast with regions, tokens, symbols and errors of
+// the file, for comparing incremental and full parsing
+func reparseDump(fs *pi.FileState) string {
+	var sb strings.Builder
+	fs.Ast.FuncDownMeFirst(0, nil, func(k ki.Ki, level int, d any) bool {
+		ast := k.(*parse.Ast)
+		if level > 1 {
+			fmt.Fprintf(&sb, "%v%v %v %v: %v\n", strings.Repeat("  ", level), ast.Nm, ast.TokReg, ast.SrcReg, ast.Src)
+		}
+		return ki.Continue
+	})
+	sb.WriteString(fs.Src.LexTagSrc())
+	var dsyms func(sm syms.SymMap, ind string)
+	dsyms = func(sm syms.SymMap, ind string) {
+		for _, sy := range sm.Slice(true) {
+			fmt.Fprintf(&sb, "%v%v %v %v %v\n", ind, sy.Name, sy.Kind, sy.Region, sy.SelectReg)
+			for _, tnm := range sy.Types.Names(true) {
+				fmt.Fprintf(&sb, "%v  type %v %v\n", ind, tnm, sy.Types[tnm].Region)
+			}
+			dsyms(sy.Children, ind+"  ")
+		}
+	}
+	dsyms(fs.ParseState.Syms, "")
+	sb.WriteString(fs.LexState.Errs.Report(0, "", false, false))
+	sb.WriteString(fs.ParseState.Errs.Report(0, "", false, false))
+	return sb.String()
+}
+
+func TestReparseLines(t *testing.T) {
+	lp, _ := pi.LangSupport.Props(filecat.Go)
+	pr := lp.Lang.Parser()
+	txt, err := lex.OpenFileBytes("testdata/reparse/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := pi.NewFileState()
+	fs.Src.InitFromString(string(txt), "main.go", filecat.Go)
+	pr.LexAll(fs)
+	pr.ParseAll(fs)
+
+	edits := []struct {
+		st, nold int
+		lines    string
+		incr     bool
+	}{
+		{16, 1, "\tfor i, v := range vals {", true},                     // change within sum
+		{21, 0, "\nfunc diff(a, b int) int {\n\treturn a - b\n}", true}, // add function
+		{10, 3, "", true},                             // delete Add method
+		{5, 0, "/* open comment", true},               // comment out Point
+		{5, 1, "", true},                              // and back
+		{14, 1, "func sum(vals []int) int {{", false}, // depth changes
+		{14, 1, "func sum(vals []int) int {", false},  // and back
+		{2, 1, "import \"strings\"", false},           // first node
+	}
+	lines := fs.Src.Lines
+	for i, ed := range edits {
+		var nlns [][]rune
+		if ed.lines != "" || ed.nold == 0 {
+			nlns = lex.RunesFromString(ed.lines)
+		}
+		var lns [][]rune
+		lns = append(lns, lines[:ed.st]...)
+		lns = append(lns, nlns...)
+		lns = append(lns, lines[ed.st+ed.nold:]...)
+		lines = lns
+		fs.Src.Lines = lines
+		incr := pr.ReparseLines(fs, ed.st, ed.nold, len(nlns))
+		if incr != ed.incr {
+			t.Errorf("edit %d: incremental: %v, want: %v", i, incr, ed.incr)
+		}
+
+		ffs := pi.NewFileState()
+		ffs.Src.SetSrc(lines, "main.go", "", filecat.Go)
+		pr.LexAll(ffs)
+		pr.ParseAll(ffs)
+		got, want := reparseDump(fs), reparseDump(ffs)
+		if got != want {
+			gl, wl := strings.Split(got, "\n"), strings.Split(want, "\n")
+			for li := 0; li < len(gl) && li < len(wl); li++ {
+				if gl[li] != wl[li] {
+					t.Errorf("edit %d: differs from full parse at line %d:\n got: %v\nwant: %v", i, li, gl[li], wl[li])
+					break
+				}
+			}
+			if len(gl) != len(wl) {
+				t.Errorf("edit %d: %d dump lines, want: %d", i, len(gl), len(wl))
+			}
+		}
+	}
+}
+
+func TestGuests(t *testing.T) {
+	src := "# Example\n\nSome text.\n\n```go\npackage main\n\nfunc Foo(a int) int {\n\treturn a\n}\n\nvar x = Foo(1, +)\n```\n\nMore text.\n"
+	fss := pi.NewFileStates("example.md", "", filecat.Markdown)
+	lp, _ := pi.LangSupport.Props(filecat.Markdown)
+	lp.Lang.ParseFile(fss, []byte(src))
+	fs := fss.Done()
+
+	if len(fs.Guests) != 1 {
+		t.Fatalf("got %d guests, want 1", len(fs.Guests))
+	}
+	gs := fs.Guests[0]
+	wreg := lex.Reg{St: lex.Pos{Ln: 4, Ch: 5}, Ed: lex.Pos{Ln: 12, Ch: 0}}
+	if gs.Lang != "go" || gs.Sup != filecat.Go || gs.Reg != wreg {
+		t.Errorf("guest: %v %v %v, want: go Go %v", gs.Lang, gs.Sup, gs.Reg, wreg)
+	}
+	if got := string(gs.FileState.Src.Lines[1]); got != "package main" {
+		t.Errorf("guest line 1: %q", got)
+	}
+	if fs.GuestAt(lex.Pos{Ln: 7, Ch: 3}) != gs || fs.GuestAt(lex.Pos{Ln: 2}) != nil {
+		t.Errorf("GuestAt did not find guest by host position")
+	}
+
+	pkg, ok := fs.Syms["main"]
+	if !ok {
+		t.Fatalf("guest package symbol not in host syms: %v", fs.Syms.Names(true))
+	}
+	foo, ok := pkg.Children["Foo"]
+	if !ok {
+		t.Fatalf("guest func symbol not in host syms: %v", pkg.Children.Names(true))
+	}
+	if foo.Filename != "example.md" || foo.Region.St.Ln != 7 {
+		t.Errorf("Foo symbol not mapped to host: %v %v", foo.Filename, foo.Region)
+	}
+
+	if len(fs.ParseState.Errs) == 0 {
+		t.Fatal("no errors from guest parse")
+	}
+	for _, e := range fs.ParseState.Errs {
+		if e.Filename != "example.md" || e.Pos.Ln != 11 {
+			t.Errorf("guest error not mapped to host: %v", e)
+		}
+	}
+
+	nerr := len(fs.ParseState.Errs)
+	lp.Lang.ParseFile(fss, []byte(src))
+	lp.Lang.ParseFile(fss, []byte(src))
+	fs = fss.Done()
+	if len(fs.Guests) != 1 || len(fs.ParseState.Errs) != nerr {
+		t.Errorf("reparse: %d guests, %d errors, want: 1, %d", len(fs.Guests), len(fs.ParseState.Errs), nerr)
+	}
+	// nesting errors from pass two of the guest are mapped too
+	lp.Lang.ParseFile(fss, []byte("Text.\n\n```go\npackage main\n\nvar y = 1 }\n```\n"))
+	fs = fss.Done()
+	if len(fs.TwoState.Errs) == 0 || fs.TwoState.Errs[0].Pos != (lex.Pos{Ln: 5, Ch: 8}) || fs.TwoState.Errs[0].Filename != "example.md" {
+		t.Errorf("guest pass two errors not mapped to host: %v", fs.TwoState.Errs)
+	}
+}
+
+// TestReparseLinesTypes does repeated edits through GoLang.ReparseLines,
+// which must not race with the type resolution from the prior edit -- run with -race
+func TestReparseLinesTypes(t *testing.T) {
+	fn, _ := filepath.Abs("testdata/reparse/main.go")
+	txt, err := lex.OpenFileBytes(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fss := pi.NewFileStates(fn, "", filecat.Go)
+	TheGoLang.ParseFile(fss, txt)
+	lns := strings.Split(string(txt), "\n")
+	for i := 0; i < 4; i++ {
+		lns[16] = fmt.Sprintf("\tfor i%d, v := range vals {", i) // change within sum
+		TheGoLang.ReparseLines(fss, []byte(strings.Join(lns, "\n")), 16, 1, 1)
+	}
+	fs := fss.Done()
+	fs.SymsMu.RLock()
+	defer fs.SymsMu.RUnlock()
+	pkg, ok := fs.Syms["main"]
+	if !ok {
+		t.Fatal("no package symbol")
+	}
+	if _, ok := pkg.Children["sum"]; !ok {
+		t.Errorf("sum not in package symbols: %v", pkg.Children.Names(true))
+	}
+}
@@ -0,0 +1,458 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golang
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goki/ki/ki"
+	"github.com/goki/pi/filecat"
+	"github.com/goki/pi/lex"
+	"github.com/goki/pi/parse"
+	"github.com/goki/pi/pi"
+	"github.com/goki/pi/syms"
+	"github.com/goki/pi/token"
+)
+
+// RefAstNames are the names of the Ast nodes that contain names that can
+// refer to symbols -- Qual* nodes have a qualifier before a .
+var RefAstNames = map[string]bool{"Name": true, "TypeNm": true, "QualName": true, "QualType": true}
+
+// NameRef is one occurrence of a name in the source
+type NameRef struct {
+
+	// the name
+	Name string
+
+	// region of the name in the source
+	Reg lex.Reg
+
+	// the name before a . preceding the name, if any (e.g., package or receiver)
+	Qual string
+
+	// true if this is the definition of a method or field
+	MemberDef bool
+}
+
+// RefTarget is the symbol that references are being found for
+type RefTarget struct {
+
+	// symbol being referenced -- can be nil for package-qualified names not in ExtSyms
+	Sym *syms.Symbol
+
+	// name of the symbol
+	Name string
+
+	// symbol is local to a function, so only the current file is searched
+	Local bool
+
+	// for local symbols, the function that has it, and for members, the type
+	Scope *syms.Symbol
+
+	// symbol is a method or field of type named Owner, referenced through selectors
+	Member bool
+
+	// for members, the name of the type that has the member
+	Owner string
+
+	// for members, true if no other type in the package has a member of the same
+	// name, so selectors with a receiver of unknown type are included
+	Unique bool
+
+	// for symbols from imported packages, the package name used to qualify them
+	Pkg string
+}
+
+// FindReferences returns the regions in the file, and in the other files of
+// its package, where the symbol at given position is used, including its
+// definition.  Local variables are only found in the current file, and
+// methods and fields are found through selectors, using the type of the
+// receiver when it is known.  The other files are read from FileStates.OpenSrc
+// if they are open, and otherwise from disk.
+func (gl *GoLang) FindReferences(fss *pi.FileStates, pos lex.Pos) []lex.FileReg {
+	fs := fss.Done()
+	if len(fs.ParseState.Scopes) == 0 || fs.ParseState.Ast == nil {
+		return nil // need a package
+	}
+
+	fs.SymsMu.RLock()
+	defer fs.SymsMu.RUnlock()
+
+	nr, ok := gl.NameRefAt(fs, pos)
+	if !ok {
+		return nil
+	}
+	fpath, _ := filepath.Abs(fs.Src.Filename)
+	tg, ofss := gl.RefTargetAt(fs, fpath, &nr, fss.OpenSrc)
+	if tg == nil {
+		return nil
+	}
+	return gl.PkgRefs(fs, fpath, tg, ofss)
+}
+
+// RefTargetAt returns the target for given name occurrence in the file, and the
+// other files of the package if the target is not local, using open for the
+// source of the files that are open (see PkgFileStates).
+// Must be called under SymsMu lock.
+func (gl *GoLang) RefTargetAt(fs *pi.FileState, fpath string, nr *NameRef, open func(fname string) ([][]rune, bool)) (*RefTarget, []*pi.FileState) {
+	tg := gl.RefTargetFor(fs, fpath, nr, nil)
+	if tg != nil && tg.Local {
+		return tg, nil
+	}
+	ofss := gl.PkgFileStates(fs, fpath, open)
+	return gl.RefTargetFor(fs, fpath, nr, ofss), ofss
+}
+
+// PkgRefs returns the regions that refer to the target in the file and
+// the other files of the package, sorted by file and position.
+// Must be called under SymsMu lock.
+func (gl *GoLang) PkgRefs(fs *pi.FileState, fpath string, tg *RefTarget, ofss []*pi.FileState) []lex.FileReg {
+	refs := gl.FileRefs(fs, fpath, tg)
+	for _, ofs := range ofss {
+		refs = append(refs, gl.FileRefs(ofs, ofs.Src.Filename, tg)...)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Filename != refs[j].Filename {
+			return refs[i].Filename < refs[j].Filename
+		}
+		return refs[i].Reg.St.IsLess(refs[j].Reg.St)
+	})
+	return refs
+}
+
+// PkgFileStates returns parsed FileStates for the other files in the package
+// of given file, with the package symbol in Syms.  Test files are only
+// included for test files.  If open is non-nil, it provides the current
+// source of files that are open for editing (e.g., FileStates.OpenSrc),
+// which is used instead of the file on disk, so unsaved changes are seen.
+func (gl *GoLang) PkgFileStates(fs *pi.FileState, fpath string, open func(fname string) ([][]rune, bool)) []*pi.FileState {
+	pr := gl.Parser()
+	var ofss []*pi.FileState
+	files, _ := filepath.Glob(filepath.Join(filepath.Dir(fpath), "*.go"))
+	for _, fn := range files {
+		if fn == fpath || strings.HasSuffix(fn, "_test.go") != strings.HasSuffix(fpath, "_test.go") {
+			continue
+		}
+		ofs := pi.NewFileState()
+		var src [][]rune
+		isOpen := false
+		if open != nil {
+			src, isOpen = open(fn)
+		}
+		if isOpen {
+			ofs.SetSrc(src, fn, "", filecat.Go)
+		} else if err := ofs.Src.OpenFile(fn); err != nil {
+			continue
+		}
+		pr.LexAll(ofs)
+		pr.ParseAll(ofs)
+		if len(ofs.ParseState.Scopes) == 0 || ofs.ParseState.Scopes[0].Name != fs.ParseState.Scopes[0].Name {
+			continue // different package
+		}
+		pkg := ofs.ParseState.Scopes[0]
+		ofs.Syms[pkg.Name] = pkg
+		ofss = append(ofss, ofs)
+	}
+	return ofss
+}
+
+// NameRefs returns all the name occurrences in the Ast of given file,
+// that have the given name if non-empty
+func (gl *GoLang) NameRefs(fs *pi.FileState, nm string) []NameRef {
+	var nrs []NameRef
+	fs.ParseState.Ast.FuncDownMeFirst(0, nil, func(k ki.Ki, level int, d any) bool {
+		ast := k.(*parse.Ast)
+		if ast.Nm == "PackageSpec" {
+			return ki.Break
+		}
+		if !RefAstNames[ast.Nm] {
+			return ki.Continue
+		}
+		src := ast.Src
+		reg := ast.SrcReg
+		if di := strings.LastIndex(src, "."); di > 0 { // qualified: first name and last name
+			qnm := src[:di]
+			src = src[di+1:]
+			if nm == "" || qnm == nm {
+				qr := NameRef{Name: qnm, Reg: lex.Reg{St: reg.St, Ed: reg.St}}
+				qr.Reg.Ed.Ch += len([]rune(qnm))
+				nrs = append(nrs, qr)
+			}
+			if nm != "" && src != nm {
+				return ki.Break
+			}
+			nrs = append(nrs, NameRef{Name: src, Reg: lex.Reg{St: lex.Pos{Ln: reg.Ed.Ln, Ch: reg.Ed.Ch - len([]rune(src))}, Ed: reg.Ed}, Qual: qnm})
+			return ki.Break
+		}
+		if src == "" || (nm != "" && src != nm) {
+			return ki.Break
+		}
+		nr := NameRef{Name: src, Reg: reg}
+		nr.Qual = SelectorQual(fs.Src.Lines, reg.St)
+		if par := ast.ParAst(); ast.Nm == "Name" && par != nil && (par.Nm == "MethDecl" || par.Nm == "NamedField") {
+			nr.MemberDef = true
+		}
+		nrs = append(nrs, nr)
+		return ki.Break
+	})
+	return nrs
+}
+
+// SelectorQual returns the name before a . that precedes given position in
+// the source, or "" if not preceded by a .
+func SelectorQual(lines [][]rune, st lex.Pos) string {
+	if st.Ln < 0 || st.Ln >= len(lines) {
+		return ""
+	}
+	ln := lines[st.Ln]
+	ch := st.Ch - 1
+	for ch >= 0 && (ln[ch] == ' ' || ln[ch] == '\t') {
+		ch--
+	}
+	if ch < 1 || ln[ch] != '.' {
+		return ""
+	}
+	ed := ch
+	for ed > 0 && (ln[ed-1] == ' ' || ln[ed-1] == '\t') {
+		ed--
+	}
+	qs := ed
+	for qs > 0 && (ln[qs-1] == '_' || lex.IsLetterOrDigit(ln[qs-1])) {
+		qs--
+	}
+	if qs == ed {
+		return "" // e.g., call or index expression
+	}
+	return string(ln[qs:ed])
+}
+
+// NameRefAt returns the name occurrence at given position in the file.
+// Must be called under SymsMu lock, as it walks the Ast.
+func (gl *GoLang) NameRefAt(fs *pi.FileState, pos lex.Pos) (NameRef, bool) {
+	for _, nr := range gl.NameRefs(fs, "") {
+		if nr.Reg.Contains(pos) || nr.Reg.Ed == pos {
+			return nr, true
+		}
+	}
+	return NameRef{}, false
+}
+
+// RefResolve resolves given name at given position in the file, returning
+// the symbol and, if it is local, the function (or method) containing the
+// position that has it.  Package-level names are resolved via FindNameScoped
+// on the package symbols.  Must be called under SymsMu lock.
+func (gl *GoLang) RefResolve(fs *pi.FileState, fpath string, nm string, pos lex.Pos) (*syms.Symbol, *syms.Symbol) {
+	var scopes syms.SymMap
+	fs.Syms.FindContainsRegion(fpath, pos, 0, token.NameFunction, &scopes)
+	fscs := scopes.Slice(false)
+	sort.Slice(fscs, func(i, j int) bool { // innermost first
+		return fscs[j].Region.St.IsLess(fscs[i].Region.St)
+	})
+	for _, sc := range fscs {
+		if sy, has := sc.Children[nm]; has {
+			return sy, sc
+		}
+	}
+	for _, psy := range fs.Syms {
+		if psy.Kind.SubCat() != token.NameScope || psy.Kind == token.NameLibrary {
+			continue
+		}
+		if sy, has := psy.Children.FindNameScoped(nm); has {
+			return sy, nil
+		}
+	}
+	return nil, nil
+}
+
+// MemberTypes returns the package types that have a method or field of given
+// name, from the package symbols of given file states -- a type is only
+// included once, from the first file state that has it
+func (gl *GoLang) MemberTypes(nm string, fss ...*pi.FileState) []*syms.Symbol {
+	var tys []*syms.Symbol
+	have := make(map[string]bool)
+	for _, fs := range fss {
+		for _, psy := range fs.Syms {
+			if psy.Kind.SubCat() != token.NameScope || psy.Kind == token.NameLibrary {
+				continue
+			}
+			for _, ty := range psy.Children {
+				if ty.Kind.SubCat() != token.NameType || have[ty.Name] {
+					continue
+				}
+				if _, has := ty.Children[nm]; has {
+					have[ty.Name] = true
+					tys = append(tys, ty)
+				}
+			}
+		}
+	}
+	return tys
+}
+
+// IsImportName returns true if given name is the package name of one of
+// the imports of given file, and is not otherwise defined at given position.
+// Must be called under SymsMu lock.
+func (gl *GoLang) IsImportName(fs *pi.FileState, fpath string, nm string, pos lex.Pos) bool {
+	if sy, _ := gl.RefResolve(fs, fpath, nm, pos); sy != nil {
+		return false
+	}
+	if _, has := fs.ExtSyms[nm]; has {
+		return true
+	}
+	var imps syms.SymMap
+	fs.Syms.FindKindScoped(token.NameLibrary, &imps)
+	for _, im := range imps {
+		if _, _, pkg := gl.ImportPathPkg(im.Name); pkg == nm {
+			return true
+		}
+	}
+	return false
+}
+
+// RefOwner returns the name of the type of the receiver of a selector with
+// given qualifier at given position, or "" if not known.
+// Must be called under SymsMu lock.
+func (gl *GoLang) RefOwner(fs *pi.FileState, fpath string, qual string, pos lex.Pos) string {
+	rsy, _ := gl.RefResolve(fs, fpath, qual, pos)
+	if rsy == nil {
+		return ""
+	}
+	if rsy.Kind.SubCat() == token.NameType {
+		return rsy.Name
+	}
+	if rsy.Type == "" || rsy.Type == TypeErr {
+		return ""
+	}
+	return rsy.NonPtrTypeName()
+}
+
+// RefTargetFor returns the target symbol for given name occurrence in the
+// file, or nil if it cannot be determined.  Must be called under SymsMu lock.
+func (gl *GoLang) RefTargetFor(fs *pi.FileState, fpath string, nr *NameRef, ofss []*pi.FileState) *RefTarget {
+	tg := &RefTarget{Name: nr.Name}
+	afss := append([]*pi.FileState{fs}, ofss...)
+	switch {
+	case nr.MemberDef:
+		for _, ty := range gl.MemberTypes(nr.Name, afss...) {
+			msy := ty.Children[nr.Name]
+			fp, _ := filepath.Abs(msy.Filename)
+			if fp == fpath && msy.Region.Contains(nr.Reg.St) {
+				tg.Sym = msy
+				tg.Scope = ty
+				tg.Owner = ty.Name
+				break
+			}
+		}
+		if tg.Sym == nil {
+			return nil
+		}
+		tg.Member = true
+	case nr.Qual != "":
+		if gl.IsImportName(fs, fpath, nr.Qual, nr.Reg.St) {
+			tg.Pkg = nr.Qual
+			if psy, has := fs.ExtSyms[nr.Qual]; has {
+				tg.Sym = psy.Children[nr.Name]
+			}
+			return tg
+		}
+		tg.Member = true
+		tg.Owner = gl.RefOwner(fs, fpath, nr.Qual, nr.Reg.St)
+		mtys := gl.MemberTypes(nr.Name, afss...)
+		if tg.Owner == "" && len(mtys) == 1 {
+			tg.Owner = mtys[0].Name
+		}
+		for _, ty := range mtys {
+			if ty.Name == tg.Owner {
+				tg.Sym = ty.Children[nr.Name]
+				tg.Scope = ty
+			}
+		}
+		if tg.Sym == nil {
+			return nil
+		}
+	default:
+		sy, sc := gl.RefResolve(fs, fpath, nr.Name, nr.Reg.St)
+		if sy == nil { // defined in another file of the package?
+			for _, ofs := range ofss {
+				if sy, _ = gl.RefResolve(ofs, ofs.Src.Filename, nr.Name, lex.PosZero); sy != nil {
+					break
+				}
+			}
+		}
+		if sy == nil {
+			return nil
+		}
+		tg.Sym = sy
+		tg.Local = sc != nil
+		tg.Scope = sc
+		return tg
+	}
+	tg.Unique = len(gl.MemberTypes(nr.Name, afss...)) <= 1
+	return tg
+}
+
+// RefMatch returns true if given name occurrence in given file refers to the target.
+// Must be called under SymsMu lock.
+func (gl *GoLang) RefMatch(fs *pi.FileState, fpath string, tg *RefTarget, nr *NameRef) bool {
+	switch {
+	case tg.Member:
+		if nr.MemberDef {
+			fp, _ := filepath.Abs(tg.Sym.Filename)
+			return fp == fpath && tg.Sym.Region.Contains(nr.Reg.St)
+		}
+		if nr.Qual == "" {
+			return false
+		}
+		if gl.IsImportName(fs, fpath, nr.Qual, nr.Reg.St) {
+			return false
+		}
+		own := gl.RefOwner(fs, fpath, nr.Qual, nr.Reg.St)
+		if own == "" {
+			return tg.Unique
+		}
+		return own == tg.Owner
+	case tg.Pkg != "":
+		return nr.Qual == tg.Pkg && gl.IsImportName(fs, fpath, nr.Qual, nr.Reg.St)
+	}
+	if nr.Qual != "" || nr.MemberDef {
+		return false
+	}
+	sy, sc := gl.RefResolve(fs, fpath, nr.Name, nr.Reg.St)
+	if sy == nil {
+		return !tg.Local // package-level symbol from another file of the package
+	}
+	if (sc != nil) != tg.Local {
+		return false
+	}
+	return SameSym(sy, tg.Sym)
+}
+
+// FileRefs returns the regions in given file that refer to the target.
+// Must be called under SymsMu lock.
+func (gl *GoLang) FileRefs(fs *pi.FileState, fpath string, tg *RefTarget) []lex.FileReg {
+	var refs []lex.FileReg
+	for _, nr := range gl.NameRefs(fs, tg.Name) {
+		if gl.RefMatch(fs, fpath, tg, &nr) {
+			refs = append(refs, lex.FileReg{Filename: fpath, Reg: nr.Reg})
+		}
+	}
+	return refs
+}
+
+// SameSym returns true if the two symbols are the same, or are copies of
+// the same symbol (e.g., from separate parsing of the same file)
+func SameSym(a, b *syms.Symbol) bool {
+	if a == b {
+		return true
+	}
+	if a == nil || b == nil || a.Name != b.Name || a.Region != b.Region {
+		return false
+	}
+	afp, _ := filepath.Abs(a.Filename)
+	bfp, _ := filepath.Abs(b.Filename)
+	return afp == bfp
+}
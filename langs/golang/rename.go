@@ -0,0 +1,161 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golang
+
+import (
+	"fmt"
+	gotoken "go/token"
+	"path/filepath"
+
+	"github.com/goki/pi/lex"
+	"github.com/goki/pi/pi"
+	"github.com/goki/pi/syms"
+	"github.com/goki/pi/token"
+)
+
+// PrepareRename returns the region of the name at given position, and an
+// error if it is not a symbol defined in this package, which can be renamed
+func (gl *GoLang) PrepareRename(fss *pi.FileStates, pos lex.Pos) (lex.Reg, error) {
+	fs := fss.Done()
+	if len(fs.ParseState.Scopes) == 0 || fs.ParseState.Ast == nil {
+		return lex.RegZero, fmt.Errorf("Rename: file has not been parsed")
+	}
+
+	fs.SymsMu.RLock()
+	defer fs.SymsMu.RUnlock()
+
+	nr, ok := gl.NameRefAt(fs, pos)
+	if !ok {
+		return lex.RegZero, fmt.Errorf("Rename: no name at position: %v", pos)
+	}
+	fpath, _ := filepath.Abs(fs.Src.Filename)
+	_, _, err := gl.RenameTarget(fs, fpath, &nr, fss.OpenSrc)
+	if err != nil {
+		return lex.RegZero, err
+	}
+	return nr.Reg, nil
+}
+
+// Rename returns the edits to rename the symbol at given position to newName,
+// for every occurrence in the file and the other files of its package.
+// It refuses to rename, returning an error, if newName is already defined in
+// the scope of the symbol (its function for locals, its type for methods and
+// fields, and the package otherwise), or if newName is used in a place where
+// it would be shadowed by, or would shadow, the renamed symbol.
+// The other files are read from FileStates.OpenSrc if they are open, so the
+// edits match their unsaved changes, and otherwise from disk.
+func (gl *GoLang) Rename(fss *pi.FileStates, pos lex.Pos, newName string) ([]lex.FileEdit, error) {
+	if !gotoken.IsIdentifier(newName) {
+		return nil, fmt.Errorf("Rename: %q is not a valid identifier", newName)
+	}
+	fs := fss.Done()
+	if len(fs.ParseState.Scopes) == 0 || fs.ParseState.Ast == nil {
+		return nil, fmt.Errorf("Rename: file has not been parsed")
+	}
+
+	fs.SymsMu.RLock()
+	defer fs.SymsMu.RUnlock()
+
+	nr, ok := gl.NameRefAt(fs, pos)
+	if !ok {
+		return nil, fmt.Errorf("Rename: no name at position: %v", pos)
+	}
+	fpath, _ := filepath.Abs(fs.Src.Filename)
+	tg, ofss, err := gl.RenameTarget(fs, fpath, &nr, fss.OpenSrc)
+	if err != nil {
+		return nil, err
+	}
+	if newName == tg.Name {
+		return nil, nil
+	}
+	refs := gl.PkgRefs(fs, fpath, tg, ofss)
+	if err := gl.RenameClash(fs, fpath, tg, ofss, refs, newName); err != nil {
+		return nil, err
+	}
+	eds := make([]lex.FileEdit, len(refs))
+	for i, fr := range refs {
+		eds[i] = lex.FileEdit{FileReg: fr, Text: newName}
+	}
+	return eds, nil
+}
+
+// RenameTarget returns the target for renaming the given name occurrence,
+// and the other files of the package if not local, using open for the source
+// of the files that are open (see PkgFileStates), with an error if the
+// symbol is not defined in this package.  Must be called under SymsMu lock.
+func (gl *GoLang) RenameTarget(fs *pi.FileState, fpath string, nr *NameRef, open func(fname string) ([][]rune, bool)) (*RefTarget, []*pi.FileState, error) {
+	tg, ofss := gl.RefTargetAt(fs, fpath, nr, open)
+	switch {
+	case tg == nil || tg.Sym == nil:
+		return nil, nil, fmt.Errorf("Rename: symbol not found: %v", nr.Name)
+	case tg.Pkg != "":
+		return nil, nil, fmt.Errorf("Rename: %v.%v is defined in another package", tg.Pkg, tg.Name)
+	}
+	sfp, _ := filepath.Abs(tg.Sym.Filename)
+	if filepath.Dir(sfp) != filepath.Dir(fpath) {
+		return nil, nil, fmt.Errorf("Rename: %v is not defined in this package", tg.Name)
+	}
+	return tg, ofss, nil
+}
+
+// RenameClash returns an error if renaming the target to newName would clash
+// with an existing symbol: one of the same name in the scope of the target,
+// or a use of newName in a place affected by the rename.
+// Must be called under SymsMu lock.
+func (gl *GoLang) RenameClash(fs *pi.FileState, fpath string, tg *RefTarget, ofss []*pi.FileState, refs []lex.FileReg, newName string) error {
+	afss := append([]*pi.FileState{fs}, ofss...)
+	var csy *syms.Symbol
+	switch {
+	case tg.Local:
+		csy, _ = tg.Scope.Children.FindName(newName)
+	case tg.Member:
+		for _, ty := range gl.MemberTypes(newName, afss...) {
+			if ty.Name == tg.Owner {
+				csy = ty.Children[newName]
+			}
+		}
+	default:
+		for _, afs := range afss {
+			for _, psy := range afs.Syms {
+				if psy.Kind.SubCat() != token.NameScope || psy.Kind == token.NameLibrary {
+					continue
+				}
+				if sy, has := psy.Children[newName]; has {
+					csy = sy
+				}
+			}
+		}
+	}
+	if csy != nil {
+		return fmt.Errorf("Rename: %v clashes with existing %v at %v:%v", newName, csy.Kind, filepath.Base(csy.Filename), csy.Region.St)
+	}
+	if tg.Member {
+		return nil // selectors are not affected by other names
+	}
+	for _, afs := range afss {
+		afp, _ := filepath.Abs(afs.Src.Filename)
+		for _, fr := range refs { // existing newName symbol would capture references
+			if fr.Filename != afp {
+				continue
+			}
+			if sy, _ := gl.RefResolve(afs, afp, newName, fr.Reg.St); sy != nil {
+				return fmt.Errorf("Rename: %v would refer to existing %v at %v:%v", newName, sy.Kind, filepath.Base(afp), fr.Reg.St)
+			}
+		}
+		for _, nr := range gl.NameRefs(afs, newName) { // renamed symbol would shadow uses of newName
+			if nr.Qual != "" || nr.MemberDef {
+				continue
+			}
+			if tg.Local && (afp != fpath || !tg.Scope.Region.Contains(nr.Reg.St)) {
+				continue
+			}
+			if _, sc := gl.RefResolve(afs, afp, newName, nr.Reg.St); sc != nil && !tg.Local {
+				continue // local names are not affected by package-level names
+			}
+			return fmt.Errorf("Rename: %v is already used at %v:%v", newName, filepath.Base(afp), nr.Reg.St)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golang
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/goki/pi/complete"
+	"github.com/goki/pi/lex"
+	"github.com/goki/pi/pi"
+	"github.com/goki/pi/syms"
+)
+
+// SignatureHelp returns the signature of the function being called at the
+// end of given text, which ends at given position within the file, with the
+// index of the argument being typed.  The function is found from the name
+// before the open paren, using TypeFromAstExpr for the receiver of methods,
+// and its parameters and return values come from its function type.
+func (gl *GoLang) SignatureHelp(fss *pi.FileStates, str string, pos lex.Pos) (sg complete.Signature, ok bool) {
+	callee, arg, ok := CallAtEnd(str)
+	if !ok {
+		return
+	}
+	fs := fss.Done()
+	if len(fs.ParseState.Scopes) == 0 {
+		return sg, false // need a package
+	}
+
+	fs.SymsMu.RLock()
+	defer fs.SymsMu.RUnlock()
+
+	pkg := fs.ParseState.Scopes[0]
+	ftyp, fsym := gl.CalleeType(fs, pkg, callee, pos)
+	switch {
+	case ftyp != nil && ftyp.Kind.Cat() == syms.Function && len(ftyp.Size) == 2:
+		npars := ftyp.Size[0]
+		nrets := ftyp.Size[1]
+		if npars+nrets > len(ftyp.Els) {
+			return sg, false
+		}
+		st := 0
+		if ftyp.Kind.SubCat() == syms.Method && npars > 0 {
+			st = 1 // skip receiver
+		}
+		for i := range ftyp.Els[st:npars] {
+			sg.Params = append(sg.Params, SigElString(&ftyp.Els[st+i]))
+		}
+		for i := range ftyp.Els[npars : npars+nrets] {
+			sg.Returns = append(sg.Returns, SigElString(&ftyp.Els[npars+i]))
+		}
+		sg.Desc = ftyp.Desc
+	case fsym != nil:
+		sg.Params = gl.FuncParams(fsym)
+	default:
+		if TraceTypes {
+			fmt.Printf("SignatureHelp: function not found: %v\n", callee)
+		}
+		return sg, false
+	}
+	sg.Name = callee
+	if di := strings.LastIndex(callee, "."); di >= 0 {
+		sg.Name = callee[di+1:]
+	}
+	sg.ActiveParam = arg
+	if np := len(sg.Params); np > 0 && arg >= np && strings.Contains(sg.Params[np-1], "...") {
+		sg.ActiveParam = np - 1 // variadic
+	}
+	return sg, true
+}
+
+// SigElString returns the string for a parameter or return value element of
+// a function type, omitting the placeholder names given to unnamed elements
+// by ParamsFromAst and RvalsFromAst
+func SigElString(el *syms.TypeEl) string {
+	if el.Name == "rval" || strings.HasPrefix(el.Name, "rval_") || strings.HasPrefix(el.Name, "param_") {
+		return el.Type
+	}
+	return el.String()
+}
+
+// CalleeType returns the function type, and symbol if available, for given
+// callee expression, which is a function name, optionally qualified by a
+// package name or preceded by an expression for the receiver of a method.
+// Must be called under SymsMu lock.
+func (gl *GoLang) CalleeType(fs *pi.FileState, pkg *syms.Symbol, callee string, pos lex.Pos) (*syms.Type, *syms.Symbol) {
+	fpath, _ := filepath.Abs(fs.Src.Filename)
+	var scopes syms.SymMap
+	scope := gl.CompletePosScope(fs, pos, fpath, &scopes)
+
+	di := strings.LastIndex(callee, ".")
+	if di < 0 { // plain function
+		fsym, got := fs.FindNameScoped(callee, scopes)
+		if !got || !gl.InferEmptySymbolType(fsym, fs, pkg) {
+			return nil, nil
+		}
+		ftyp, _ := gl.FindTypeName(fsym.Type, fs, pkg)
+		return ftyp, fsym
+	}
+	recv := callee[:di]
+	fnm := callee[di+1:]
+	if _, got := fs.FindNameScoped(recv, scopes); !got {
+		if psym, has := gl.PkgSyms(fs, pkg.Children, recv); has { // package function
+			fsym, has := psym.Children[fnm]
+			if !has {
+				return nil, nil
+			}
+			ftyp, _ := gl.FindTypeName(fsym.Type, fs, psym)
+			if ftyp == nil {
+				ftyp = psym.Types[fsym.Type]
+			}
+			return ftyp, fsym
+		}
+	}
+	pr := gl.Parser()
+	lfs := pr.ParseString(recv, fpath, fs.Src.Sup)
+	if lfs == nil {
+		return nil, nil
+	}
+	start, _ := gl.CompleteAstStart(lfs.ParseState.Ast, scope)
+	if start == nil {
+		return nil, nil
+	}
+	start.SrcReg.St = pos
+	typ, _, got := gl.TypeFromAstExprStart(fs, pkg, pkg, start)
+	if !got || typ == nil {
+		return nil, nil
+	}
+	if typ.Kind == syms.Ptr && len(typ.Els) > 0 {
+		if dtyp, _ := gl.FindTypeName(typ.Els[0].Type, fs, pkg); dtyp != nil {
+			typ = dtyp
+		}
+	}
+	if mt, has := typ.Meths[fnm]; has {
+		return mt, nil
+	}
+	if fel := typ.Els.ByName(fnm); fel != nil { // field of func type
+		ftyp, _ := gl.FindTypeName(fel.Type, fs, pkg)
+		return ftyp, nil
+	}
+	return nil, nil
+}
+
+// CallAtEnd returns the callee expression for the innermost function call
+// that is still open at the end of given text, and the index of the argument
+// being typed (number of commas in the call), skipping over strings, runes,
+// comments, and nested brackets.  Returns false if not in a call.
+func CallAtEnd(str string) (callee string, arg int, ok bool) {
+	src := []rune(str)
+	sz := len(src)
+	type brack struct {
+		pos   int
+		br    rune
+		comma int
+	}
+	var stack []brack
+	for i := 0; i < sz; i++ {
+		r := src[i]
+		switch r {
+		case '"', '\'', '`':
+			for i++; i < sz && src[i] != r; i++ {
+				if src[i] == '\\' && r != '`' {
+					i++
+				}
+			}
+		case '/':
+			if i+1 < sz && src[i+1] == '/' {
+				for i < sz && src[i] != '\n' {
+					i++
+				}
+			}
+		case '(', '[', '{':
+			stack = append(stack, brack{pos: i, br: r})
+		case ')', ']', '}':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case ',':
+			if len(stack) > 0 {
+				stack[len(stack)-1].comma++
+			}
+		}
+	}
+	if len(stack) == 0 {
+		return "", 0, false
+	}
+	top := stack[len(stack)-1]
+	if top.br != '(' {
+		return "", 0, false
+	}
+	ed := top.pos
+	for ed > 0 && (src[ed-1] == ' ' || src[ed-1] == '\t') {
+		ed--
+	}
+	if ed == 0 || !(src[ed-1] == '_' || src[ed-1] == ')' || src[ed-1] == ']' || lex.IsLetterOrDigit(src[ed-1])) {
+		return "", 0, false // e.g., a conversion or grouping paren
+	}
+	st := ed
+	depth := 0
+back:
+	for st > 0 {
+		r := src[st-1]
+		switch {
+		case r == ')' || r == ']':
+			depth++
+		case r == '(' || r == '[':
+			if depth == 0 {
+				break back
+			}
+			depth--
+		case depth == 0 && r != '.' && r != '_' && !lex.IsLetterOrDigit(r):
+			break back
+		}
+		st--
+	}
+	return string(src[st:ed]), top.comma, true
+}
@@ -0,0 +1,30 @@
+package decls
+
+import (
+	"fmt"
+	str "strings"
+)
+
+const Pi = 3.14
+
+var (
+	x, y int
+	name = "pi"
+)
+
+type Point struct {
+	X, Y float32
+	Name string `json:"name"`
+}
+
+type Shape interface {
+	Area() float32
+}
+
+func (pt *Point) Add(o Point) Point {
+	return Point{X: pt.X + o.X, Y: pt.Y + o.Y}
+}
+
+func Hello(nm string) (string, error) {
+	return fmt.Sprintf("hello %v", str.ToUpper(nm)), nil
+}
@@ -0,0 +1,14 @@
+package exprs
+
+func Exprs(a, b int, s []string, p *int) {
+	c := a*b + (a-b)/2
+	d := s[1:len(s)-1]
+	e := *p + c
+	f := &e
+	g := map[string][]int{"a": {1, 2}, "b": nil}
+	h := func(x int) int { return x << 2 }(a)
+	var i interface{} = g
+	j, ok := i.(map[string][]int)
+	k := !ok && a >= b || c != 0
+	_, _, _, _, _ = d, f, h, j, k
+}
@@ -0,0 +1,29 @@
+package stmts
+
+func Stmts(vals []int, m map[string]int) int {
+	sum := 0
+	for i, v := range vals {
+		if v < 0 {
+			continue
+		} else if v > 100 {
+			break
+		}
+		sum += v * i
+	}
+	for i := 0; i < 10; i++ {
+		sum--
+	}
+	switch {
+	case sum > 10:
+		sum = 10
+	default:
+		sum = 0
+	}
+	if n, ok := m["key"]; ok {
+		sum = n
+	}
+	defer func() {
+		sum = 0
+	}()
+	return sum
+}
@@ -0,0 +1,14 @@
+package recover
+
+func f(a int) int {
+	x := a
+	x := := 1
+	:= 2
+	y := x + a
+	return y
+}
+
+func g() {
+	z := f(1 + )
+	v := z
+}
@@ -0,0 +1,16 @@
+package main
+
+type Foo struct {
+	Bar int
+}
+
+func (f *Foo) Get() int {
+	return f.Bar
+}
+
+func main() {
+	f := &Foo{}
+	f.Get()
+	x := f.Bar + 1
+	Helper(x)
+}
@@ -0,0 +1,6 @@
+package main
+
+func Helper(y int) int {
+	var f Foo
+	return f.Get() + y
+}
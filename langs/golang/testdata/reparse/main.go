@@ -0,0 +1,26 @@
+package main
+
+import "fmt"
+
+// Point is a point
+type Point struct {
+	X, Y int
+}
+
+// Add adds points
+func (pt *Point) Add(o Point) Point {
+	return Point{X: pt.X + o.X, Y: pt.Y + o.Y}
+}
+
+func sum(vals []int) int {
+	tot := 0
+	for _, v := range vals {
+		tot += v
+	}
+	return tot
+}
+
+func main() {
+	pt := Point{X: 1}
+	fmt.Println(pt.Add(pt), sum([]int{1, 2}))
+}
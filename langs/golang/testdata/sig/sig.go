@@ -0,0 +1,19 @@
+package sig
+
+type Buf struct {
+	n int
+}
+
+func (b *Buf) Write(p []byte, n int) (int, error) {
+	return n, nil
+}
+
+func Join(elems []string, sep string) string {
+	return sep
+}
+
+func use() {
+	var b Buf
+	b.Write(nil, 1)
+	Join([]string{"a", "b"}, ",")
+}
@@ -77,7 +77,7 @@ func (gl *GoLang) InferSymbolType(sy *syms.Symbol, fs *pi.FileState, pkg *syms.S
 				} else {
 					astyp = ast.ChildAst(len(ast.Kids) - 1)
 					vty, ok := gl.TypeFromAst(fs, pkg, nil, astyp)
-					if ok {
+					if ok && vty != nil {
 						sy.Type = SymTypeNameForPkg(vty, pkg)
 						// if TraceTypes {
 						// 	fmt.Printf("namevar: %v  type: %v from ast\n", sy.Name, sy.Type)
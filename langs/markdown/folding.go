@@ -0,0 +1,76 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package markdown
+
+import (
+	"strings"
+
+	"github.com/goki/pi/lex"
+	"github.com/goki/pi/pi"
+)
+
+// FoldingRanges returns the foldable regions of the file, by heading level:
+// each heading folds the lines up to the next heading of the same or higher
+// level, excluding trailing blank lines.  Fenced code blocks also fold.
+func (ml *MarkdownLang) FoldingRanges(fs *pi.FileState) []pi.FoldRange {
+	lns := fs.Src.Lines
+	nln := len(lns)
+	var frs []pi.FoldRange
+	type head struct {
+		ln, lev int
+		fr      int // index in frs
+	}
+	var heads []head // stack of open headings
+	lastText := -1   // last non-blank line
+	endHeads := func(lev int) {
+		for len(heads) > 0 && heads[len(heads)-1].lev >= lev {
+			hd := heads[len(heads)-1]
+			heads = heads[:len(heads)-1]
+			frs[hd.fr].Reg.Ed = lex.Pos{Ln: lastText, Ch: len(lns[lastText])}
+		}
+	}
+	fence := -1 // start of code fence
+	for ln := 0; ln < nln; ln++ {
+		str := string(lns[ln])
+		if strings.HasPrefix(str, "```") {
+			if fence < 0 {
+				fence = ln
+			} else {
+				frs = append(frs, pi.FoldRange{Reg: lex.Reg{St: lex.Pos{Ln: fence, Ch: len(lns[fence])}, Ed: lex.Pos{Ln: ln, Ch: 0}}})
+				fence = -1
+			}
+		} else if fence < 0 {
+			if lev := HeadingLevel(str); lev > 0 {
+				endHeads(lev)
+				heads = append(heads, head{ln: ln, lev: lev, fr: len(frs)})
+				frs = append(frs, pi.FoldRange{Reg: lex.Reg{St: lex.Pos{Ln: ln, Ch: len(lns[ln])}}})
+			}
+		}
+		if strings.TrimSpace(str) != "" {
+			lastText = ln
+		}
+	}
+	endHeads(0)
+	nfr := frs[:0]
+	for _, fr := range frs {
+		if fr.Reg.Ed.Ln > fr.Reg.St.Ln {
+			nfr = append(nfr, fr)
+		}
+	}
+	return nfr
+}
+
+// HeadingLevel returns the level of the markdown heading on given line,
+// i.e., the number of leading # characters, or 0 if it is not a heading
+func HeadingLevel(str string) int {
+	lev := 0
+	for lev < len(str) && str[lev] == '#' {
+		lev++
+	}
+	if lev == 0 || lev > 6 || (lev < len(str) && str[lev] != ' ' && str[lev] != '\t') {
+		return 0
+	}
+	return lev
+}
@@ -62,8 +62,13 @@ func (ml *MarkdownLang) ParseFile(fss *pi.FileStates, txt []byte) {
 	pfs := fss.StartProc(txt) // current processing one
 	pr.LexAll(pfs)
 	ml.OpenBibfile(fss, pfs)
+	// no parser of its own -- fenced code is parsed by the guest language
+	pfs.ParseState.Errs.Reset()
+	pfs.SymsMu.Lock()
+	pfs.Syms.Reset()
+	pfs.SymsMu.Unlock()
+	pfs.ParseGuests()
 	fss.EndProc() // now done
-	// no parser
 }
 
 func (ml *MarkdownLang) LexLine(fs *pi.FileState, line int, txt []rune) lex.Line {
@@ -110,6 +115,11 @@ func (ml *MarkdownLang) Lookup(fss *pi.FileStates, str string, pos lex.Pos) (ld
 	return
 }
 
+func (ml *MarkdownLang) FindReferences(fss *pi.FileStates, pos lex.Pos) []lex.FileReg {
+	// n/a
+	return nil
+}
+
 func (ml *MarkdownLang) CompleteEdit(fs *pi.FileStates, text string, cp int, comp complete.Completion, seed string) (ed complete.Edit) {
 	// if the original is ChildByName() and the cursor is between d and B and the comp is Children,
 	// then delete the portion after "Child" and return the new comp and the number or runes past
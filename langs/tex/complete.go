@@ -47,6 +47,11 @@ func (tl *TexLang) Lookup(fss *pi.FileStates, str string, pos lex.Pos) (ld compl
 	return
 }
 
+func (tl *TexLang) FindReferences(fss *pi.FileStates, pos lex.Pos) []lex.FileReg {
+	// n/a
+	return nil
+}
+
 func (tl *TexLang) CompleteEdit(fss *pi.FileStates, text string, cp int, comp complete.Completion, seed string) (ed complete.Edit) {
 	// if the original is ChildByName() and the cursor is between d and B and the comp is Children,
 	// then delete the portion after "Child" and return the new comp and the number or runes past
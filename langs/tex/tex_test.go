@@ -0,0 +1,27 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tex
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/goki/pi/filecat"
+	"github.com/goki/pi/parse/parsetest"
+	"github.com/goki/pi/pi"
+)
+
+func init() {
+	pi.LangSupport.OpenStd()
+}
+
+// update writes the parsetest golden files from the current output
+var update = flag.Bool("update", false, "update parsetest golden files with the current lexer output")
+
+// TestGrammar checks the lexer output for the snippets in testdata/parse
+// against their golden files -- run with -update to rewrite them
+func TestGrammar(t *testing.T) {
+	parsetest.Dir(t, TheTexLang.Parser(), "testdata/parse", filecat.TeX, *update, ".tex")
+}
@@ -57,12 +57,22 @@ const (
 	PopState
 
 	// SetGuestLex means install the Name (must be a prior action) as the guest
-	// lexer -- it will take over lexing until PopGuestLex is called
+	// lexer -- it will take over lexing until PopGuestLex is called, and the
+	// region it lexes is recorded in State.Guests for parsing by the guest language
 	SetGuestLex
 
 	// PopGuestLex removes the current guest lexer and returns to the original
 	// language lexer
 	PopGuestLex
 
+	// PushDelim means push the PushState state onto the state stack, along
+	// with a delimiter captured from the current match, for strings whose
+	// terminator comes from the source (heredocs, raw strings) -- the delimiter
+	// is the first submatch of a Regexp match (or all of the match), and is
+	// matched by a Delim rule under a CurState rule for the state, which then
+	// does PopState.  Must come before Next, as it reads the match at the
+	// current position.
+	PushDelim
+
 	ActionsN
 )
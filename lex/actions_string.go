@@ -24,12 +24,13 @@ func _() {
 	_ = x[PopState-8]
 	_ = x[SetGuestLex-9]
 	_ = x[PopGuestLex-10]
-	_ = x[ActionsN-11]
+	_ = x[PushDelim-11]
+	_ = x[ActionsN-12]
 }
 
-const _Actions_name = "NextNameNumberQuotedQuotedRawEOLReadUntilPushStatePopStateSetGuestLexPopGuestLexActionsN"
+const _Actions_name = "NextNameNumberQuotedQuotedRawEOLReadUntilPushStatePopStateSetGuestLexPopGuestLexPushDelimActionsN"
 
-var _Actions_index = [...]uint8{0, 4, 8, 14, 20, 29, 32, 41, 50, 58, 69, 80, 88}
+var _Actions_index = [...]uint8{0, 4, 8, 14, 20, 29, 32, 41, 50, 58, 69, 80, 89, 97}
 
 func (i Actions) String() string {
 	if i < 0 || i >= Actions(len(_Actions_index)-1) {
@@ -363,7 +363,7 @@ func (fl *File) PrevDepth(ln int) int {
 	}
 	lx := fl.LexAt(pos)
 	depth := lx.Tok.Depth
-	if lx.Tok.Tok.IsPunctGpLeft() {
+	if lx.Tok.Tok.IsPunctGpLeft() || lx.Tok.Tok == token.IndentBlock {
 		depth++
 	}
 	return depth
@@ -582,3 +582,52 @@ func (fl *File) NextEosAnyDepth(stpos Pos) (Pos, bool) {
 	}
 	return ep, false
 }
+
+// ApplyEdits returns a copy of the lines with given edits applied, which
+// must be sorted by position and not overlap, with regions in terms of the
+// rune positions in the lines (e.g., from Ast SrcReg) -- new lines in
+// the text of the edits start new lines
+func ApplyEdits(lines [][]rune, eds []FileEdit) [][]rune {
+	nlines := len(lines)
+	out := make([][]rune, 0, nlines)
+	var cur []rune
+	pos := PosZero
+	copyTo := func(ep Pos) {
+		for ; pos.Ln < ep.Ln && pos.Ln < nlines; pos.Ln++ {
+			if pos.Ch < len(lines[pos.Ln]) {
+				cur = append(cur, lines[pos.Ln][pos.Ch:]...)
+			}
+			out = append(out, cur)
+			cur = nil
+			pos.Ch = 0
+		}
+		if pos.Ln >= nlines {
+			return
+		}
+		ln := lines[pos.Ln]
+		ech := ep.Ch
+		if ech > len(ln) {
+			ech = len(ln)
+		}
+		if pos.Ch < ech {
+			cur = append(cur, ln[pos.Ch:ech]...)
+		}
+		pos.Ch = ech
+	}
+	for _, ed := range eds {
+		copyTo(ed.Reg.St)
+		for i, ts := range strings.Split(ed.Text, "\n") {
+			if i > 0 {
+				out = append(out, cur)
+				cur = nil
+			}
+			cur = append(cur, []rune(ts)...)
+		}
+		pos = ed.Reg.Ed
+	}
+	copyTo(Pos{Ln: nlines})
+	if cur != nil || pos.Ln < nlines {
+		out = append(out, cur)
+	}
+	return out
+}
@@ -52,6 +52,19 @@ const (
 	// come first!
 	AnyRune
 
+	// Regexp means match the regular expression in String, anchored at the
+	// current position (plus Offset) -- the length of the match, which must
+	// be non-empty, is used for the Next action.  The expression only sees
+	// the rest of the current line.
+	Regexp
+
+	// Delim means match the delimiter captured by the PushDelim action for
+	// the current state, using String as a template for the terminator, in
+	// which $1 is replaced by the delimiter (e.g., "\"$1" for a Rust raw
+	// string) -- if String is empty, the terminator is the delimiter itself
+	// (e.g., a heredoc)
+	Delim
+
 	MatchesN
 )
 
@@ -20,12 +20,14 @@ func _() {
 	_ = x[WhiteSpace-4]
 	_ = x[CurState-5]
 	_ = x[AnyRune-6]
-	_ = x[MatchesN-7]
+	_ = x[Regexp-7]
+	_ = x[Delim-8]
+	_ = x[MatchesN-9]
 }
 
-const _Matches_name = "StringStrNameLetterDigitWhiteSpaceCurStateAnyRuneMatchesN"
+const _Matches_name = "StringStrNameLetterDigitWhiteSpaceCurStateAnyRuneRegexpDelimMatchesN"
 
-var _Matches_index = [...]uint8{0, 6, 13, 19, 24, 34, 42, 49, 57}
+var _Matches_index = [...]uint8{0, 6, 13, 19, 24, 34, 42, 49, 55, 60, 68}
 
 func (i Matches) String() string {
 	if i < 0 || i >= Matches(len(_Matches_index)-1) {
@@ -33,6 +33,12 @@ type PassTwo struct {
 
 	// specific tokens to recognize at the end of a line that trigger an EOS (Go)
 	EolToks token.KeyTokenList `desc:"specific tokens to recognize at the end of a line that trigger an EOS (Go)"`
+
+	// use the indentation of each line for block structure, for off-side-rule languages (python, yaml): an IndentBlock token is inserted at the end of a line followed by a more indented line, and a DedentBlock token at the start of a line for each indented block that it closes -- these act like braces for the nesting depth, and an EOS follows each DedentBlock -- lines within brackets are continuations and are not considered
+	Indent bool `desc:"use the indentation of each line for block structure, for off-side-rule languages (python, yaml): an IndentBlock token is inserted at the end of a line followed by a more indented line, and a DedentBlock token at the start of a line for each indented block that it closes -- these act like braces for the nesting depth, and an EOS follows each DedentBlock -- lines within brackets are continuations and are not considered"`
+
+	// number of columns for a tab, in computing the indentation for Indent -- 0 = 8
+	TabSize int `desc:"number of columns for a tab, in computing the indentation for Indent -- 0 = 8"`
 }
 
 // TwoState is the state maintained for the PassTwo process
@@ -47,6 +53,9 @@ type TwoState struct {
 	// stack of nesting tokens
 	NestStack []token.Tokens `desc:"stack of nesting tokens"`
 
+	// stack of indentation columns of the open indented blocks, for Indent
+	IndentStack []int `desc:"stack of indentation columns of the open indented blocks, for Indent"`
+
 	// any error messages accumulated during lexing specifically
 	Errs ErrorList `desc:"any error messages accumulated during lexing specifically"`
 }
@@ -55,6 +64,8 @@ type TwoState struct {
 func (ts *TwoState) Init() {
 	ts.Pos = PosZero
 	ts.NestStack = ts.NestStack[0:0]
+	ts.IndentStack = ts.IndentStack[0:0]
+	ts.Errs.Reset()
 }
 
 // SetSrc sets the source we're operating on
@@ -87,6 +98,8 @@ func (ts *TwoState) NestStackStr() string {
 			str += "bracket [ "
 		case token.PunctGpLBrace:
 			str += "brace { "
+		case token.IndentBlock:
+			str += "indent "
 		}
 	}
 	return str
@@ -147,15 +160,23 @@ func (pt *PassTwo) NestDepth(ts *TwoState) {
 	// 	ts.Src.Lexs = append(ts.Src.Lexs, Line{})
 	// 	*ts.Src.Lines = append(*ts.Src.Lines, []rune{})
 	// }
+	lastLn := -1 // last line with tokens, for Indent
 	for ts.Pos.Ln < nlines {
 		sz := len(ts.Src.Lexs[ts.Pos.Ln])
 		if sz == 0 {
 			ts.NextLine()
 			continue
 		}
+		if pt.Indent && ts.Pos.Ch == 0 {
+			pt.IndentLine(ts, lastLn)
+			sz = len(ts.Src.Lexs[ts.Pos.Ln])
+			lastLn = ts.Pos.Ln
+		}
 		lx := ts.Src.LexAt(ts.Pos)
 		tok := lx.Tok.Tok
-		if tok.IsPunctGpLeft() {
+		if tok == token.IndentBlock || tok == token.DedentBlock {
+			// depth set by IndentLine
+		} else if tok.IsPunctGpLeft() {
 			lx.Tok.Depth = len(ts.NestStack) // depth increments AFTER -- this turns out to be ESSENTIAL!
 			pt.PushNest(ts, tok)
 		} else if tok.IsPunctGpRight() {
@@ -169,12 +190,101 @@ func (pt *PassTwo) NestDepth(ts *TwoState) {
 			ts.NextLine()
 		}
 	}
+	if pt.Indent && lastLn >= 0 {
+		pt.DedentTo(ts, Pos{lastLn, len(ts.Src.Lexs[lastLn])}, 0)
+	}
 	stsz := len(ts.NestStack)
 	if stsz > 0 {
 		pt.Error(ts, "mismatched grouping -- end of file with these left unmatched: "+ts.NestStackStr())
 	}
 }
 
+// IndentLine does the Indent processing at the start of the current line,
+// given the last prior line with tokens: if the line is more indented than
+// the current block, an IndentBlock is added at the end of the last line,
+// and if it is less indented, a DedentBlock is inserted for each block that
+// it closes.  Lines within brackets are skipped, as are lines with only
+// whitespace.
+func (pt *PassTwo) IndentLine(ts *TwoState, lastLn int) {
+	if ns := len(ts.NestStack); ns > 0 && ts.NestStack[ns-1] != token.IndentBlock {
+		return // within brackets
+	}
+	ln := ts.Pos.Ln
+	lxs := ts.Src.Lexs[ln]
+	fi := 0 // first non-whitespace token
+	for fi < len(lxs) && lxs[fi].Tok.Tok == token.TextWhitespace {
+		fi++
+	}
+	if fi == len(lxs) {
+		return
+	}
+	col := pt.IndentCol(ts.Src.Lines[ln], lxs[fi].St)
+	top := 0
+	if ni := len(ts.IndentStack); ni > 0 {
+		top = ts.IndentStack[ni-1]
+	}
+	switch {
+	case col > top:
+		ip := Pos{ln, fi}
+		if lastLn >= 0 {
+			ip = Pos{lastLn, len(ts.Src.Lexs[lastLn])}
+		}
+		ilx := Lex{Tok: token.KeyToken{Tok: token.IndentBlock, Depth: len(ts.NestStack)}, St: lxs[fi].St, Ed: lxs[fi].St}
+		if ip.Ln != ln {
+			elx := ts.Src.Lexs[ip.Ln][ip.Ch-1]
+			ilx.St, ilx.Ed = elx.Ed, elx.Ed
+		}
+		ts.Src.Lexs[ip.Ln].Insert(ip.Ch, ilx)
+		pt.PushNest(ts, token.IndentBlock)
+		ts.IndentStack = append(ts.IndentStack, col)
+	case col < top:
+		pt.DedentTo(ts, Pos{ln, fi}, col)
+		ni := len(ts.IndentStack)
+		if (ni == 0 && col != 0) || (ni > 0 && ts.IndentStack[ni-1] != col) {
+			pt.Error(ts, "unindent does not match any outer indentation level")
+		}
+	}
+}
+
+// DedentTo inserts a DedentBlock token at given position for each indented
+// block that is indented more than given column
+func (pt *PassTwo) DedentTo(ts *TwoState, pos Pos, col int) {
+	for ni := len(ts.IndentStack); ni > 0 && ts.IndentStack[ni-1] > col; ni-- {
+		ns := len(ts.NestStack)
+		if ns == 0 || ts.NestStack[ns-1] != token.IndentBlock {
+			break // unmatched brackets, reported at end
+		}
+		ts.NestStack = ts.NestStack[:ns-1]
+		ts.IndentStack = ts.IndentStack[:ni-1]
+		dlx := Lex{Tok: token.KeyToken{Tok: token.DedentBlock, Depth: len(ts.NestStack)}}
+		if lxs := ts.Src.Lexs[pos.Ln]; pos.Ch < len(lxs) {
+			dlx.St, dlx.Ed = lxs[pos.Ch].St, lxs[pos.Ch].St
+		} else if pos.Ch > 0 {
+			dlx.St, dlx.Ed = lxs[pos.Ch-1].Ed, lxs[pos.Ch-1].Ed
+		}
+		ts.Src.Lexs[pos.Ln].Insert(pos.Ch, dlx)
+		pos.Ch++
+	}
+}
+
+// IndentCol returns the column of given char position in the line, with
+// tabs advancing to the next multiple of the TabSize
+func (pt *PassTwo) IndentCol(src []rune, ch int) int {
+	tsz := pt.TabSize
+	if tsz == 0 {
+		tsz = 8
+	}
+	col := 0
+	for i := 0; i < ch && i < len(src); i++ {
+		if src[i] == '\t' {
+			col = (col/tsz + 1) * tsz
+		} else {
+			col++
+		}
+	}
+	return col
+}
+
 // Perform nesting depth computation on only one line, starting at
 // given initial depth -- updates the given line
 func (pt *PassTwo) NestDepthLine(line Line, initDepth int) {
@@ -259,9 +369,13 @@ func (pt *PassTwo) EosDetectPos(ts *TwoState, pos Pos, nln int) {
 				continue
 			}
 		}
+		if pt.Indent {
+			pt.IndentEos(ts)
+			sz = len(ts.Src.Lexs[ts.Pos.Ln])
+		}
 		ep := Pos{ts.Pos.Ln, sz - 1} // end of line token
 		elx := ts.Src.LexAt(ep)
-		if pt.Eol {
+		if pt.Eol && !pt.Indent {
 			sp := Pos{ts.Pos.Ln, 0} // start of line token
 			slx := ts.Src.LexAt(sp)
 			if slx.Tok.Depth == elx.Tok.Depth {
@@ -276,3 +390,34 @@ func (pt *PassTwo) EosDetectPos(ts *TwoState, pos Pos, nln int) {
 		ts.NextLine()
 	}
 }
+
+// IndentEos inserts the EOS tokens for the current line with Indent, in place
+// of the Eol check: after each DedentBlock, and at the end of the statement
+// in the line if Eol, unless it ends with an IndentBlock that starts a block
+func (pt *PassTwo) IndentEos(ts *TwoState) {
+	ln := ts.Pos.Ln
+	ci := 0
+	for ; ci < len(ts.Src.Lexs[ln]) && ts.Src.Lexs[ln][ci].Tok.Tok == token.DedentBlock; ci++ {
+		ts.Src.InsertEos(Pos{ln, ci})
+		ci++
+	}
+	st := ci
+	for st < len(ts.Src.Lexs[ln]) && ts.Src.Lexs[ln][st].Tok.Tok == token.IndentBlock {
+		st++ // indented first line
+	}
+	ed := len(ts.Src.Lexs[ln]) - 1
+	for ed >= st && ts.Src.Lexs[ln][ed].Tok.Tok == token.DedentBlock {
+		ed-- // end of file
+	}
+	if pt.Eol && ed >= st {
+		slx, elx := ts.Src.Lexs[ln][st], ts.Src.Lexs[ln][ed]
+		if elx.Tok.Tok != token.IndentBlock && elx.Tok.Tok != token.EOS && slx.Tok.Depth == elx.Tok.Depth {
+			ts.Src.InsertEos(Pos{ln, ed})
+			ed++
+		}
+	}
+	for ci = ed + 1; ci < len(ts.Src.Lexs[ln]); ci++ {
+		ts.Src.InsertEos(Pos{ln, ci})
+		ci++
+	}
+}
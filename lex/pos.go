@@ -99,6 +99,31 @@ func (tr Reg) Contains(ps Pos) bool {
 	return ps.IsLess(tr.Ed) && (tr.St == ps || tr.St.IsLess(ps))
 }
 
+// FileReg is a region within a given file
+type FileReg struct {
+
+	// full filename with path
+	Filename string `desc:"full filename with path"`
+
+	// region within the file
+	Reg Reg `desc:"region within the file"`
+}
+
+// String satisfies the fmt.Stringer interface
+func (fr FileReg) String() string {
+	return fmt.Sprintf("%s:%s", fr.Filename, fr.Reg.St)
+}
+
+// FileEdit is a replacement of a region within a given file with new text
+type FileEdit struct {
+
+	// region within the file that is replaced
+	FileReg `desc:"region within the file that is replaced"`
+
+	// new text for the region
+	Text string `desc:"new text for the region"`
+}
+
 ////////////////////////////////////////////////////////////////////
 //  EosPos
 
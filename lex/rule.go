@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 	"unicode"
@@ -91,6 +93,9 @@ type Rule struct {
 
 	// NameMap lookup map -- created during Compile
 	NmMap map[string]*Rule `inactive:"+" json:"-" xml:"-" desc:"NameMap lookup map -- created during Compile"`
+
+	// compiled regular expression for Regexp match, anchored at the start -- created during Compile
+	Re *regexp.Regexp `view:"-" json:"-" xml:"-" desc:"compiled regular expression for Regexp match, anchored at the start -- created during Compile"`
 }
 
 var KiT_Rule = kit.Types.AddType(&Rule{}, RuleProps)
@@ -128,6 +133,16 @@ func (lr *Rule) Compile(ls *State) bool {
 	}
 	valid := true
 	lr.ComputeMatchLen(ls)
+	lr.Re = nil
+	if lr.Match == Regexp && !lr.Off {
+		re, err := regexp.Compile(`^(?:` + lr.String + `)`)
+		if err != nil {
+			ls.Error(0, fmt.Sprintf("match = Regexp has invalid regular expression: %v", err), lr)
+			valid = false
+		} else {
+			lr.Re = re
+		}
+	}
 	if lr.NameMap {
 		if !lr.CompileNameMap(ls) {
 			valid = false
@@ -173,6 +188,15 @@ func (lr *Rule) Validate(ls *State) bool {
 				valid = false
 				ls.Error(0, "match = String or StrName but String is empty", lr)
 			}
+		case Regexp:
+			if len(lr.String) == 0 {
+				valid = false
+				ls.Error(0, "match = Regexp but String is empty -- must have regular expression to match", lr)
+			}
+		case Delim:
+			if _, ok := lr.Par.(*Rule); !ok || lr.Par.(*Rule).Match != CurState {
+				ls.Error(0, "match = Delim should be within a CurState rule for the state pushed by PushDelim", lr)
+			}
 		case CurState:
 			for _, act := range lr.Acts {
 				if act == Next {
@@ -195,6 +219,7 @@ func (lr *Rule) Validate(ls *State) bool {
 	}
 
 	hasPos := false
+	hasNext := false
 	for _, act := range lr.Acts {
 		if act >= Name && act <= EOL {
 			hasPos = true
@@ -203,6 +228,19 @@ func (lr *Rule) Validate(ls *State) bool {
 			valid = false
 			ls.Error(0, "action = Next incompatible with action that reads item such as Name, Number, Quoted", lr)
 		}
+		if act == Next {
+			hasNext = true
+		}
+		if act == PushDelim {
+			if hasNext {
+				valid = false
+				ls.Error(0, "action = PushDelim must come before Next, as it reads the match at the current position", lr)
+			}
+			if len(lr.PushState) == 0 {
+				valid = false
+				ls.Error(0, "action = PushDelim must have state to push in PushState -- is empty", lr)
+			}
+		}
 	}
 
 	if lr.Token.Cat() == token.Keyword && lr.Match != StrName {
@@ -239,6 +277,10 @@ func (lr *Rule) ComputeMatchLen(ls *State) {
 		lr.MatchLen = 0
 	case AnyRune:
 		lr.MatchLen = lr.Offset + 1 + lr.SizeAdj
+	case Regexp:
+		lr.MatchLen = 0 // set in State.MatchLen by each match in IsMatch
+	case Delim:
+		lr.MatchLen = 0 // set in State.MatchLen by each match in IsMatch
 	}
 }
 
@@ -380,10 +422,72 @@ func (lr *Rule) IsMatch(ls *State) bool {
 			return false
 		}
 		return true
+	case Regexp:
+		if lr.Re == nil {
+			return false
+		}
+		sz := ls.MatchRegexp(lr.Re, lr.Offset)
+		if sz == 0 {
+			return false
+		}
+		ls.MatchLen = lr.Offset + sz + lr.SizeAdj
+		return true
+	case Delim:
+		term := lr.DelimTerm(ls)
+		if term == "" {
+			return false
+		}
+		sz := len([]rune(term))
+		str, ok := ls.String(lr.Offset, sz)
+		if !ok || str != term {
+			return false
+		}
+		ls.MatchLen = lr.Offset + sz + lr.SizeAdj
+		return true
 	}
 	return false
 }
 
+// CurMatchLen returns the length of source matched by this rule at the
+// current position: MatchLen for a match of fixed length, and otherwise the
+// State MatchLen set by IsMatch, as the rules are shared across files
+func (lr *Rule) CurMatchLen(ls *State) int {
+	if lr.Match == Regexp || lr.Match == Delim {
+		return ls.MatchLen
+	}
+	return lr.MatchLen
+}
+
+// DelimTerm returns the terminator for a Delim match, from the String
+// template and the delimiter of the current state, or "" if none
+func (lr *Rule) DelimTerm(ls *State) string {
+	delim, ok := ls.CurDelim()
+	if !ok || delim == "" {
+		return ""
+	}
+	if lr.String == "" {
+		return delim
+	}
+	return strings.ReplaceAll(lr.String, "$1", delim)
+}
+
+// MatchDelim returns the delimiter captured from the current match, for the
+// PushDelim action: the first submatch of a Regexp match, or else all of the
+// match
+func (lr *Rule) MatchDelim(ls *State) string {
+	sz := lr.CurMatchLen(ls) - lr.Offset - lr.SizeAdj
+	str, ok := ls.String(lr.Offset, sz)
+	if !ok {
+		return ""
+	}
+	if lr.Match == Regexp && lr.Re != nil {
+		if sm := lr.Re.FindStringSubmatch(str); len(sm) > 1 {
+			return sm[1]
+		}
+	}
+	return str
+}
+
 // IsMatchPos tests if the rule matches position
 func (lr *Rule) IsMatchPos(ls *State) bool {
 	lsz := len(ls.Src)
@@ -439,6 +543,13 @@ func (lr *Rule) TargetLen(ls *State) int {
 		return lr.Offset + 1
 	case CurState:
 		return 0
+	case Delim:
+		return lr.Offset + len([]rune(lr.DelimTerm(ls)))
+	case Regexp:
+		if lr.Re == nil {
+			return lr.Offset
+		}
+		return lr.Offset + ls.MatchRegexp(lr.Re, lr.Offset)
 	}
 	return 0
 }
@@ -447,7 +558,7 @@ func (lr *Rule) TargetLen(ls *State) int {
 func (lr *Rule) DoAct(ls *State, act Actions, tok *token.KeyToken) {
 	switch act {
 	case Next:
-		ls.Next(lr.MatchLen)
+		ls.Next(lr.CurMatchLen(ls))
 	case Name:
 		ls.ReadName()
 	case Number:
@@ -473,6 +584,7 @@ func (lr *Rule) DoAct(ls *State, act Actions, tok *token.KeyToken) {
 			if lx != nil {
 				ls.GuestLex = lx
 				ls.SaveStack = ls.Stack.Clone()
+				ls.StartGuest(ls.LastName)
 			}
 		}
 	case PopGuestLex:
@@ -480,7 +592,12 @@ func (lr *Rule) DoAct(ls *State, act Actions, tok *token.KeyToken) {
 			ls.Stack = ls.SaveStack
 			ls.SaveStack = nil
 		}
+		if ls.GuestLex != nil {
+			ls.EndGuest(Pos{ls.Ln, ls.Pos})
+		}
 		ls.GuestLex = nil
+	case PushDelim:
+		ls.PushDelim(lr.PushState, lr.MatchDelim(ls))
 	}
 }
 
@@ -510,6 +627,10 @@ func (lr *Rule) WriteGrammar(writer io.Writer, depth int) {
 		}
 	} else {
 		ind := indent.Tabs(depth)
+		nmstr := lr.Nm
+		if lr.Off {
+			nmstr = "// OFF: " + nmstr
+		}
 		gpstr := ""
 		if lr.HasChildren() {
 			gpstr = " {"
@@ -518,15 +639,15 @@ func (lr *Rule) WriteGrammar(writer io.Writer, depth int) {
 		if lr.Pos != AnyPos {
 			offstr += fmt.Sprintf("@%v:", lr.Pos)
 		}
-		if lr.Offset > 0 {
-			offstr += fmt.Sprintf("+%d:", lr.Offset)
+		if lr.Offset != 0 {
+			offstr += fmt.Sprintf("%+d:", lr.Offset)
 		}
 		actstr := ""
 		if len(lr.Acts) > 0 {
 			actstr = "\t do: "
 			for _, ac := range lr.Acts {
 				actstr += ac.String()
-				if ac == PushState {
+				if ac == PushState || ac == PushDelim {
 					actstr += ": " + lr.PushState
 				} else if ac == ReadUntil {
 					actstr += ": \"" + lr.Until + "\""
@@ -534,13 +655,23 @@ func (lr *Rule) WriteGrammar(writer io.Writer, depth int) {
 				actstr += "; "
 			}
 		}
+		optstr := ""
+		if lr.NameMap || lr.SizeAdj != 0 {
+			optstr = "\t opts: "
+			if lr.NameMap {
+				optstr += "NameMap; "
+			}
+			if lr.SizeAdj != 0 {
+				optstr += fmt.Sprintf("SizeAdj: %d; ", lr.SizeAdj)
+			}
+		}
 		if lr.Desc != "" {
-			fmt.Fprintf(writer, "%v// %v %v \n", ind, lr.Nm, lr.Desc)
+			fmt.Fprintf(writer, "%v// %v %v \n", ind, nmstr, lr.Desc)
 		}
 		if (lr.Match >= Letter && lr.Match <= WhiteSpace) || lr.Match == AnyRune {
-			fmt.Fprintf(writer, "%v%v:\t\t %v\t\t if %v%v%v%v\n", ind, lr.Nm, lr.Token, offstr, lr.Match, actstr, gpstr)
+			fmt.Fprintf(writer, "%v%v:\t\t %v\t\t if %v%v%v%v%v\n", ind, nmstr, lr.Token, offstr, lr.Match, actstr, optstr, gpstr)
 		} else {
-			fmt.Fprintf(writer, "%v%v:\t\t %v\t\t if %v%v == \"%v\"%v%v\n", ind, lr.Nm, lr.Token, offstr, lr.Match, lr.String, actstr, gpstr)
+			fmt.Fprintf(writer, "%v%v:\t\t %v\t\t if %v%v == \"%v\"%v%v%v\n", ind, nmstr, lr.Token, offstr, lr.Match, lr.String, actstr, optstr, gpstr)
 		}
 		if lr.HasChildren() {
 			w := tabwriter.NewWriter(writer, 4, 4, 2, ' ', 0)
@@ -554,6 +685,128 @@ func (lr *Rule) WriteGrammar(writer io.Writer, depth int) {
 	}
 }
 
+// SetGrammar sets the rule from the grammar string written by WriteGrammar
+// after the rule name and colon, without any group { -- i.e., the Token,
+// followed by if, the match conditions, and optional do: actions and opts:
+func (lr *Rule) SetGrammar(str string) error {
+	ii := strings.Index(str, " if ")
+	if ii < 0 {
+		return fmt.Errorf("lex.Rule %v: expected: Token if Match, got: %v", lr.Nm, str)
+	}
+	if err := lr.Token.FromString(strings.TrimSpace(str[:ii])); err != nil {
+		return err
+	}
+	str = strings.TrimSpace(str[ii+len(" if "):])
+	lr.Pos = AnyPos
+	lr.Offset = 0
+	for len(str) > 0 && (str[0] == '@' || str[0] == '+' || str[0] == '-') {
+		ci := strings.Index(str, ":")
+		if ci < 0 {
+			return fmt.Errorf("lex.Rule %v: offset or position missing colon: %v", lr.Nm, str)
+		}
+		var err error
+		if str[0] == '@' {
+			err = lr.Pos.FromString(str[1:ci])
+		} else {
+			lr.Offset, err = strconv.Atoi(str[:ci])
+		}
+		if err != nil {
+			return err
+		}
+		str = str[ci+1:]
+	}
+	mi := strings.IndexFunc(str, func(r rune) bool { return !IsLetterOrDigit(r) })
+	if mi < 0 {
+		mi = len(str)
+	}
+	if err := lr.Match.FromString(str[:mi]); err != nil {
+		return err
+	}
+	str = str[mi:]
+	lr.String = ""
+	if strings.HasPrefix(str, ` == "`) {
+		str = str[len(` == "`):]
+		ei := -1 // closing quote is the last one before actions and opts, as String can contain "
+		for i := len(str) - 1; i >= 0; i-- {
+			if str[i] != '"' {
+				continue
+			}
+			rest := str[i+1:]
+			trest := strings.TrimSpace(rest)
+			if trest == "" || ((rest[0] == ' ' || rest[0] == '\t') && (strings.HasPrefix(trest, "do: ") || strings.HasPrefix(trest, "opts: "))) {
+				ei = i
+				break
+			}
+		}
+		if ei < 0 {
+			return fmt.Errorf("lex.Rule %v: String missing closing quote: %v", lr.Nm, str)
+		}
+		lr.String = str[:ei]
+		str = str[ei+1:]
+	}
+	str = strings.TrimSpace(str)
+	lr.Acts = nil
+	lr.PushState = ""
+	lr.Until = ""
+	if strings.HasPrefix(str, "do: ") {
+		str = str[len("do: "):]
+		for {
+			str = strings.TrimSpace(str)
+			if str == "" || strings.HasPrefix(str, "opts: ") {
+				break
+			}
+			ei := strings.IndexAny(str, ":;")
+			if ei < 0 {
+				return fmt.Errorf("lex.Rule %v: action missing semicolon: %v", lr.Nm, str)
+			}
+			var act Actions
+			if err := act.FromString(strings.TrimSpace(str[:ei])); err != nil {
+				return err
+			}
+			lr.Acts = append(lr.Acts, act)
+			if str[ei] == ';' {
+				str = str[ei+1:]
+				continue
+			}
+			str = strings.TrimSpace(str[ei+1:])
+			si := strings.Index(str, ";")
+			if act == ReadUntil && strings.HasPrefix(str, `"`) {
+				si = strings.Index(str[1:], `";`) + 1
+				lr.Until = str[1:si]
+				si++
+			} else if si >= 0 {
+				lr.PushState = strings.TrimSpace(str[:si])
+			}
+			if si <= 0 {
+				return fmt.Errorf("lex.Rule %v: action missing semicolon: %v", lr.Nm, str)
+			}
+			str = str[si+1:]
+		}
+	}
+	lr.NameMap = false
+	lr.SizeAdj = 0
+	if strings.HasPrefix(str, "opts: ") {
+		for _, opt := range strings.Split(str[len("opts: "):], ";") {
+			opt = strings.TrimSpace(opt)
+			switch {
+			case opt == "":
+			case opt == "NameMap":
+				lr.NameMap = true
+			case strings.HasPrefix(opt, "SizeAdj:"):
+				var err error
+				if lr.SizeAdj, err = strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(opt, "SizeAdj:"))); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("lex.Rule %v: unknown option: %v", lr.Nm, opt)
+			}
+		}
+	} else if str != "" {
+		return fmt.Errorf("lex.Rule %v: unexpected text at end: %v", lr.Nm, str)
+	}
+	return nil
+}
+
 var RuleProps = ki.Props{
 	"EnumType:Flag": ki.KiT_Flags,
 	// "CallMethods": ki.PropSlice{
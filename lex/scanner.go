@@ -0,0 +1,123 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lex
+
+import (
+	"bufio"
+	"io"
+	"unicode/utf8"
+)
+
+// ScanFunc is called by Scanner.Scan for each token, with the line index
+// (0 indexed), the source runes for the line, and the token, whose St, Ed
+// are rune positions within src.  The src is only valid during the call,
+// as it is reused for the next line.  Return false to stop scanning.
+type ScanFunc func(ln int, src []rune, lx Lex) bool
+
+// Scanner runs a lexer Rule tree over source read from an io.Reader, one
+// line at a time, without keeping the lines or their tokens around as
+// lex.File does, so it can be used for very large files and pipes.
+// The state stack is carried over from one line to the next, as in the
+// full lexer, so multi-line comments and strings work as usual.
+// Only the lexer is run -- there is no PassTwo nesting depth or EOS
+// detection, which require the whole file.
+type Scanner struct {
+
+	// the top-level lexer rule to run, which must already be compiled and validated, e.g., Parser.Lexer after InitAll
+	Lexer *Rule `desc:"the top-level lexer rule to run, which must already be compiled and validated, e.g., Parser.Lexer after InitAll"`
+
+	// lexing state, with the current line in Ln -- its Errs only has the errors for the current line, as they are passed to ErrFunc and then cleared for each line
+	State State `desc:"lexing state, with the current line in Ln -- its Errs only has the errors for the current line, as they are passed to ErrFunc and then cleared for each line"`
+
+	// if set, this is called for each lexing error, after the tokens of its line -- the error is not kept after the call
+	ErrFunc func(err *Error) `desc:"if set, this is called for each lexing error, after the tokens of its line -- the error is not kept after the call"`
+
+	// total number of lexing errors
+	NErrs int `desc:"total number of lexing errors"`
+
+	rd   *bufio.Reader
+	lbuf []byte
+	rbuf []rune
+}
+
+// NewScanner returns a new Scanner for given lexer, reading from given
+// reader, with given filename used for error messages
+func NewScanner(lexer *Rule, r io.Reader, fname string) *Scanner {
+	sc := &Scanner{Lexer: lexer}
+	sc.State.Init()
+	sc.State.Time.Now()
+	sc.State.Filename = fname
+	sc.rd = bufio.NewReader(r)
+	return sc
+}
+
+// Scan reads and lexes the source line by line, calling fun for each token
+// in order, including comments, until the end of the source, or fun returns
+// false.  Returns any error from reading other than io.EOF.  Lexing errors
+// are passed to ErrFunc and counted in NErrs, but not kept, and the rest of
+// a line with an error is skipped.
+func (sc *Scanner) Scan(fun ScanFunc) error {
+	ls := &sc.State
+	for ln := 0; ; ln++ {
+		b, err := sc.readLine()
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if err == io.EOF && len(b) == 0 {
+			return nil
+		}
+		if n := len(b); n > 0 && b[n-1] == '\n' {
+			b = b[:n-1]
+		}
+		src := sc.runes(b)
+		lx, cm := ls.Lex[:0], ls.Comments[:0]
+		ls.SetLine(src)
+		ls.Lex, ls.Comments = lx, cm
+		ls.Ln = ln
+		ls.Errs.Reset()
+		for !ls.AtEol() {
+			if sc.Lexer.LexStart(ls) == nil {
+				break
+			}
+		}
+		for _, t := range MergeLines(ls.Lex, ls.Comments) {
+			if !fun(ln, src, t) {
+				return nil
+			}
+		}
+		sc.NErrs += len(ls.Errs)
+		if sc.ErrFunc != nil {
+			for _, e := range ls.Errs {
+				sc.ErrFunc(e)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// readLine reads the next line, including the newline, into the line buffer
+func (sc *Scanner) readLine() ([]byte, error) {
+	sc.lbuf = sc.lbuf[:0]
+	for {
+		b, err := sc.rd.ReadSlice('\n')
+		sc.lbuf = append(sc.lbuf, b...)
+		if err != bufio.ErrBufferFull {
+			return sc.lbuf, err
+		}
+	}
+}
+
+// runes decodes given bytes into the rune buffer
+func (sc *Scanner) runes(b []byte) []rune {
+	sc.rbuf = sc.rbuf[:0]
+	for len(b) > 0 {
+		r, sz := utf8.DecodeRune(b)
+		sc.rbuf = append(sc.rbuf, r)
+		b = b[sz:]
+	}
+	return sc.rbuf
+}
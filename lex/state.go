@@ -6,6 +6,8 @@ package lex
 
 import (
 	"fmt"
+	"io"
+	"regexp"
 	"strings"
 	"unicode"
 
@@ -51,6 +53,9 @@ type State struct {
 	// the current rune read by NextRune
 	Ch rune `desc:"the current rune read by NextRune"`
 
+	// length of source matched by the last Regexp or Delim rule match, set in Rule.IsMatch -- kept here instead of in the Rule, which is shared by all files lexed with it
+	MatchLen int `desc:"length of source matched by the last Regexp or Delim rule match, set in Rule.IsMatch -- kept here instead of in the Rule, which is shared by all files lexed with it"`
+
 	// state stack
 	Stack Stack `desc:"state stack"`
 
@@ -63,6 +68,9 @@ type State struct {
 	// copy of stack at point when guest lexer was installed -- restore when popped
 	SaveStack Stack `desc:"copy of stack at point when guest lexer was installed -- restore when popped"`
 
+	// regions of the source lexed by guest lexers, recorded by the SetGuestLex and PopGuestLex actions -- the last one is still open while GuestLex is set
+	Guests []GuestReg `desc:"regions of the source lexed by guest lexers, recorded by the SetGuestLex and PopGuestLex actions -- the last one is still open while GuestLex is set"`
+
 	// time stamp for lexing -- set at start of new lex process
 	Time nptime.Time `desc:"time stamp for lexing -- set at start of new lex process"`
 
@@ -77,9 +85,36 @@ func (ls *State) Init() {
 	ls.Ln = 0
 	ls.SetLine(nil)
 	ls.SaveStack = nil
+	ls.Guests = nil
 	ls.Errs.Reset()
 }
 
+// GuestReg is a region of the source lexed by a guest lexer for another
+// language, e.g., a fenced code block in markdown
+type GuestReg struct {
+
+	// name of the guest language, as read by the Name action for SetGuestLex
+	Lang string `desc:"name of the guest language, as read by the Name action for SetGuestLex"`
+
+	// region of the source in the guest language
+	Reg Reg `desc:"region of the source in the guest language"`
+}
+
+// StartGuest records the start of a guest region for given language
+// at the current position
+func (ls *State) StartGuest(lang string) {
+	ls.Guests = append(ls.Guests, GuestReg{Lang: lang, Reg: Reg{St: Pos{ls.Ln, ls.Pos}, Ed: PosErr}})
+}
+
+// EndGuest ends the open guest region, if any, at given position
+func (ls *State) EndGuest(pos Pos) {
+	ng := len(ls.Guests)
+	if ng == 0 || ls.Guests[ng-1].Reg.Ed != PosErr {
+		return
+	}
+	ls.Guests[ng-1].Reg.Ed = pos
+}
+
 // SetLine sets a new line for parsing and initializes the lex output and pos
 func (ls *State) SetLine(src []rune) {
 	ls.Src = src
@@ -123,6 +158,37 @@ func (ls *State) Rune(off int) (rune, bool) {
 	return ls.Src[idx], true
 }
 
+// MatchRegexp returns the number of runes matched by given regular expression,
+// which must be anchored with ^, at given offset from current position --
+// returns 0 if no match, or out of range
+func (ls *State) MatchRegexp(re *regexp.Regexp, off int) int {
+	idx := ls.Pos + off
+	if idx >= len(ls.Src) {
+		return 0
+	}
+	loc := re.FindReaderIndex(&runesReader{src: ls.Src[idx:]})
+	if loc == nil {
+		return 0
+	}
+	return loc[1]
+}
+
+// runesReader is an io.RuneReader over runes, with each rune having a size
+// of 1, so the positions of a regexp match are rune indexes
+type runesReader struct {
+	src []rune
+	pos int
+}
+
+func (rr *runesReader) ReadRune() (rune, int, error) {
+	if rr.pos >= len(rr.src) {
+		return 0, 0, io.EOF
+	}
+	r := rr.src[rr.pos]
+	rr.pos++
+	return r, 1, nil
+}
+
 // Next moves to next position using given increment in source line -- returns false if at end
 func (ls *State) Next(inc int) bool {
 	sz := len(ls.Src)
@@ -183,9 +249,10 @@ func (ls *State) PushState(st string) {
 	ls.Stack.Push(st)
 }
 
-// CurState returns the current state
+// CurState returns the current state, without any delimiter from PushDelim
 func (ls *State) CurState() string {
-	return ls.Stack.Top()
+	cur, _, _ := strings.Cut(ls.Stack.Top(), DelimSep)
+	return cur
 }
 
 // PopState pops state off of stack
@@ -199,7 +266,25 @@ func (ls *State) MatchState(st string) bool {
 	if sz == 0 {
 		return false
 	}
-	return ls.Stack[sz-1] == st
+	cur, _, _ := strings.Cut(ls.Stack[sz-1], DelimSep)
+	return cur == st
+}
+
+// DelimSep separates the state from the delimiter captured by the PushDelim
+// action in the state stack, so the delimiter is saved with the stack at the
+// end of each line, for strings that continue across lines
+const DelimSep = "\x00"
+
+// PushDelim pushes state onto stack, with given delimiter
+func (ls *State) PushDelim(st, delim string) {
+	ls.Stack.Push(st + DelimSep + delim)
+}
+
+// CurDelim returns the delimiter captured by PushDelim for the current state,
+// and false if there is none
+func (ls *State) CurDelim() (string, bool) {
+	_, delim, has := strings.Cut(ls.Stack.Top(), DelimSep)
+	return delim, has
 }
 
 // ReadNameTmp reads a standard alpha-numeric_ name and returns it.
@@ -0,0 +1,91 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/goki/pi/complete"
+	"github.com/goki/pi/lex"
+)
+
+// CompletionTriggers are the characters that trigger completion in the client,
+// in addition to typing identifiers: . for selectors and @ for citations
+var CompletionTriggers = []string{".", "@"}
+
+// Completion handles the textDocument/completion request, using
+// Lang.CompleteLine to get the matches and Lang.CompleteEdit to
+// get the edit for each match.
+func (sv *Server) Completion(params json.RawMessage) (interface{}, error) {
+	var cp CompletionParams
+	if err := DecodeParams(params, &cp); err != nil {
+		return nil, err
+	}
+	dc, err := sv.Doc(cp.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	cl := &CompletionList{Items: []CompletionItem{}}
+	if dc.Lang == nil {
+		return cl, nil
+	}
+	pos := dc.PosFromLSP(cp.Position)
+	ln := dc.Lines[pos.Ln]
+	text := strings.TrimLeft(string(ln[:pos.Ch]), " \t")
+	md := dc.Lang.CompleteLine(dc.FileStates, text, pos)
+	if len(md.Matches) == 0 {
+		return cl, nil
+	}
+	line := string(ln)
+	cpb := len(string(ln[:pos.Ch])) // byte offset of cursor in line
+	for i := range md.Matches {
+		c := &md.Matches[i]
+		ed := dc.Lang.CompleteEdit(dc.FileStates, line, cpb, *c, md.Seed)
+		cl.Items = append(cl.Items, dc.CompletionItem(pos, c, &ed, md.Seed, i))
+	}
+	return cl, nil
+}
+
+// CompletionItem returns the LSP CompletionItem for given completion and
+// edit at given cursor position.  The seed before the cursor is replaced
+// along with ForwardDelete runes after it, and any CursorAdjust is
+// represented by a snippet final tab stop.
+func (dc *Document) CompletionItem(pos lex.Pos, c *complete.Completion, ed *complete.Edit, seed string, idx int) CompletionItem {
+	ci := CompletionItem{Label: c.Label, Detail: c.Desc, FilterText: c.Text}
+	if ci.Label == "" {
+		ci.Label = c.Text
+	}
+	ci.Kind = int(CompletionKindForIcon(c.Icon))
+	ci.SortText = fmt.Sprintf("%05d", idx) // keep our order
+
+	ln := dc.Lines[pos.Ln]
+	st := pos
+	if sr := []rune(seed); len(sr) <= pos.Ch && string(ln[pos.Ch-len(sr):pos.Ch]) == seed {
+		st.Ch -= len(sr)
+	}
+	end := pos
+	end.Ch += ed.ForwardDelete
+	if end.Ch > len(ln) {
+		end.Ch = len(ln)
+	}
+	nt := ed.NewText
+	if ed.CursorAdjust != 0 {
+		ntr := []rune(nt)
+		cpos := len(ntr) + ed.CursorAdjust
+		if cpos >= 0 && cpos <= len(ntr) {
+			nt = SnippetEscape(string(ntr[:cpos])) + "$0" + SnippetEscape(string(ntr[cpos:]))
+			ci.InsertTextFormat = SnippetFormat
+		}
+	}
+	ci.TextEdit = &TextEdit{Range: dc.RangeFromReg(lex.Reg{St: st, Ed: end}), NewText: nt}
+	return ci
+}
+
+// SnippetEscape escapes the characters that are special in snippet syntax
+func SnippetEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `$`, `\$`, `}`, `\}`).Replace(s)
+}
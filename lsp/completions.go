@@ -6,6 +6,7 @@ package lsp
 
 import (
 	"github.com/goki/ki/kit"
+	"github.com/goki/pi/token"
 )
 
 // CompletionKind is the Language Server Protocol (LSP) CompletionKind, which
@@ -55,3 +56,74 @@ const (
 
 	CompletionKindN
 )
+
+// CompletionKindTokenMap maps between completion kinds and token.Tokens
+var CompletionKindTokenMap = map[CompletionKind]token.Tokens{
+	CkText:          token.Text,
+	CkMethod:        token.NameMethod,
+	CkFunction:      token.NameFunction,
+	CkConstructor:   token.NameConstructor,
+	CkField:         token.NameField,
+	CkVariable:      token.NameVar,
+	CkClass:         token.NameClass,
+	CkInterface:     token.NameInterface,
+	CkModule:        token.NameModule,
+	CkProperty:      token.NameProperty,
+	CkValue:         token.Literal,
+	CkEnum:          token.NameEnum,
+	CkKeyword:       token.Keyword,
+	CkEnumMember:    token.NameEnumMember,
+	CkConstant:      token.NameConstant,
+	CkStruct:        token.NameStruct,
+	CkEvent:         token.NameEvent,
+	CkOperator:      token.Operator,
+	CkTypeParameter: token.NameTypeParam,
+}
+
+// TokenCompletionKindMap maps from tokens to LSP CompletionKind
+var TokenCompletionKindMap map[token.Tokens]CompletionKind
+
+// IconTokenMap maps from the complete.Completion Icon names used by
+// the languages to the corresponding token.Tokens
+var IconTokenMap = map[string]token.Tokens{
+	"var":      token.NameVar,
+	"const":    token.NameConstant,
+	"field":    token.NameField,
+	"type":     token.NameType,
+	"types":    token.NameType,
+	"method":   token.NameMethod,
+	"function": token.NameFunction,
+}
+
+func init() {
+	TokenCompletionKindMap = make(map[token.Tokens]CompletionKind, len(CompletionKindTokenMap))
+	for c, t := range CompletionKindTokenMap {
+		TokenCompletionKindMap[t] = c
+	}
+	TokenCompletionKindMap[token.NameType] = CkClass
+}
+
+// CompletionKindForToken returns the CompletionKind for given token,
+// trying the sub-category and category of the token if not found directly
+func CompletionKindForToken(tk token.Tokens) CompletionKind {
+	if ck, ok := TokenCompletionKindMap[tk]; ok {
+		return ck
+	}
+	if ck, ok := TokenCompletionKindMap[tk.SubCat()]; ok {
+		return ck
+	}
+	if ck, ok := TokenCompletionKindMap[tk.Cat()]; ok {
+		return ck
+	}
+	return CkText
+}
+
+// CompletionKindForIcon returns the CompletionKind for given
+// complete.Completion Icon name, via IconTokenMap
+func CompletionKindForIcon(icon string) CompletionKind {
+	tk, ok := IconTokenMap[icon]
+	if !ok {
+		return CkText
+	}
+	return CompletionKindForToken(tk)
+}
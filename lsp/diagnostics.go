@@ -0,0 +1,93 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"strings"
+
+	"github.com/goki/ki/kit"
+	"github.com/goki/pi/lex"
+	"github.com/goki/pi/pi"
+)
+
+// DiagnosticsMax is the maximum number of diagnostics reported from each
+// source of errors for a document
+var DiagnosticsMax = 100
+
+// Diagnostics returns the diagnostics for the current processed state of the
+// document, from the lexer, pass two nesting, and parser error lists,
+// with Source "lexer", "nesting", and "parser" respectively.
+// Only the first error on any given line is reported from each source.
+func (dc *Document) Diagnostics() []Diagnostic {
+	ds := []Diagnostic{}
+	if dc.Lang == nil {
+		return ds
+	}
+	fs := dc.FileStates.Done()
+	ds = dc.ErrDiagnostics(ds, fs, fs.LexState.Errs, "lexer", "Lexer: ")
+	ds = dc.ErrDiagnostics(ds, fs, fs.TwoState.Errs, "nesting", "PassTwo: ")
+	ds = dc.ErrDiagnostics(ds, fs, fs.ParseState.Errs, "parser", "")
+	return ds
+}
+
+// ErrDiagnostics adds the diagnostics for given error list to ds, with
+// given source, and removing given prefix from the error messages.
+// The range of each diagnostic is the lexical token at the error position.
+func (dc *Document) ErrDiagnostics(ds []Diagnostic, fs *pi.FileState, errs lex.ErrorList, source, prefix string) []Diagnostic {
+	if len(errs) == 0 {
+		return ds
+	}
+	el := make(lex.ErrorList, len(errs))
+	copy(el, errs)
+	el.RemoveMultiples()
+	for i, er := range el {
+		if i >= DiagnosticsMax {
+			break
+		}
+		d := Diagnostic{Severity: SevError, Source: source}
+		d.Message = strings.TrimPrefix(er.Msg, prefix)
+		if !kit.IfaceIsNil(er.Rule) {
+			d.Code = er.Rule.Name()
+		}
+		d.Range = dc.RangeFromReg(dc.ErrReg(fs, er.Pos))
+		ds = append(ds, d)
+	}
+	return ds
+}
+
+// ErrReg returns the region for an error at given source position:
+// the extent of the lexical token at that position if there is one,
+// and otherwise just the one character at that position.
+func (dc *Document) ErrReg(fs *pi.FileState, pos lex.Pos) lex.Reg {
+	if nln := len(dc.Lines); pos.Ln >= nln { // e.g., end of file errors
+		pos = lex.Pos{Ln: nln - 1, Ch: len(dc.Lines[nln-1])}
+	}
+	reg := lex.Reg{St: pos, Ed: pos}
+	reg.Ed.Ch++
+	if pos.Ln < 0 {
+		return reg
+	}
+	if pos.Ln < fs.Src.NLines() {
+		for _, lx := range fs.Src.Lexs[pos.Ln] {
+			if pos.Ch >= lx.St && pos.Ch < lx.Ed {
+				reg.St.Ch = lx.St
+				reg.Ed.Ch = lx.Ed
+				break
+			}
+		}
+	}
+	if lnln := len(dc.Lines[pos.Ln]); reg.Ed.Ch > lnln {
+		reg.Ed.Ch = lnln
+		if reg.St.Ch > lnln {
+			reg.St.Ch = lnln
+		}
+	}
+	return reg
+}
+
+// PublishDiagnostics sends the current diagnostics for given document to the client
+func (sv *Server) PublishDiagnostics(dc *Document) {
+	sv.Notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{URI: dc.URI, Version: dc.Version, Diagnostics: dc.Diagnostics()})
+}
@@ -0,0 +1,305 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goki/pi/filecat"
+	"github.com/goki/pi/lex"
+	"github.com/goki/pi/pi"
+	"github.com/goki/pi/syms"
+	"github.com/goki/pi/token"
+)
+
+// WorkspaceSymbolMax is the maximum number of symbols returned from workspace/symbol
+var WorkspaceSymbolMax = 500
+
+// WorkspaceExcludes are directory names that are not searched for workspace symbols,
+// in addition to any starting with . or _
+var WorkspaceExcludes = map[string]bool{"testdata": true, "vendor": true, "node_modules": true}
+
+// DocumentSymbol handles the textDocument/documentSymbol request, returning
+// the hierarchical outline of symbols defined in the document
+func (sv *Server) DocumentSymbol(params json.RawMessage) (interface{}, error) {
+	var dp DocumentSymbolParams
+	if err := DecodeParams(params, &dp); err != nil {
+		return nil, err
+	}
+	dc, err := sv.Doc(dp.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	fs := dc.FileStates.Done()
+	fs.SymsMu.RLock()
+	defer fs.SymsMu.RUnlock()
+	ds := dc.DocSymbols(fs.Syms)
+	if ds == nil {
+		ds = []DocumentSymbol{}
+	}
+	return ds, nil
+}
+
+// DocSymbols returns the DocumentSymbols for the symbols in given map that are
+// defined in this document, sorted by position.  Scoping symbols (e.g.,
+// packages) are not included themselves, but their children are.
+// The children of functions (local variables) are not included.
+func (dc *Document) DocSymbols(sm syms.SymMap) []DocumentSymbol {
+	var ds []DocumentSymbol
+	for _, sy := range SortedSyms(sm) {
+		if sy.Kind.SubCat() == token.NameScope {
+			if sy.Kind != token.NameLibrary {
+				ds = append(ds, dc.DocSymbols(sy.Children)...)
+			}
+			continue
+		}
+		if sy.IsTemp() || !SameFile(sy.Filename, dc.Filename) {
+			continue
+		}
+		d := DocumentSymbol{Name: sy.Name, Detail: sy.Detail, Kind: int(SymbolKindForToken(sy.Kind))}
+		d.Range = dc.RangeFromReg(sy.Region)
+		d.SelectionRange = dc.RangeFromReg(dc.NameReg(sy))
+		if sy.Kind.SubCat() != token.NameFunction {
+			d.Children = dc.DocSymbols(sy.Children)
+		}
+		ds = append(ds, d)
+	}
+	sort.SliceStable(ds, func(i, j int) bool {
+		return PosLess(ds[i].Range.Start, ds[j].Range.Start)
+	})
+	return ds
+}
+
+// NameReg returns the region of the name of given symbol, searching the first
+// line of its SelectReg -- returns SelectReg if the name is not found there
+func (dc *Document) NameReg(sy *syms.Symbol) lex.Reg {
+	reg := sy.SelectReg
+	st := reg.St
+	if st.Ln < 0 || st.Ln >= len(dc.Lines) || st.Ch > len(dc.Lines[st.Ln]) || sy.Name == "" {
+		return reg
+	}
+	ln := string(dc.Lines[st.Ln][st.Ch:])
+	bi := strings.Index(ln, sy.Name)
+	if bi < 0 {
+		return reg
+	}
+	st.Ch += len([]rune(ln[:bi]))
+	ed := st
+	ed.Ch += len([]rune(sy.Name))
+	if reg.Ed.IsLess(ed) {
+		return reg
+	}
+	return lex.Reg{St: st, Ed: ed}
+}
+
+// WorkspaceSymbol handles the workspace/symbol request, searching the
+// package-level symbols for all the directories in the workspace, and the
+// symbols of all open documents, for names containing the query
+// (case insensitive).
+func (sv *Server) WorkspaceSymbol(params json.RawMessage) (interface{}, error) {
+	var wp WorkspaceSymbolParams
+	if err := DecodeParams(params, &wp); err != nil {
+		return nil, err
+	}
+	query := strings.ToLower(wp.Query)
+	res := []SymbolInformation{}
+	have := make(map[string]bool)
+	var add func(sm syms.SymMap, cont string)
+	add = func(sm syms.SymMap, cont string) {
+		for _, sy := range SortedSyms(sm) {
+			if len(res) >= WorkspaceSymbolMax {
+				return
+			}
+			if sy.Kind.SubCat() == token.NameScope {
+				if sy.Kind != token.NameLibrary {
+					add(sy.Children, sy.Name)
+				}
+				continue
+			}
+			if sy.IsTemp() || sy.Filename == "" {
+				continue
+			}
+			if query == "" || strings.Contains(strings.ToLower(sy.Name), query) {
+				key := sy.Filename + ":" + sy.Name + ":" + sy.Region.St.String()
+				if !have[key] {
+					have[key] = true
+					si := SymbolInformation{Name: sy.Name, Kind: int(SymbolKindForToken(sy.Kind)), ContainerName: cont}
+					si.Location = sv.RegLocation(sy.Filename, sy.Region)
+					res = append(res, si)
+				}
+			}
+			if sy.Kind.SubCat() != token.NameFunction {
+				add(sy.Children, sy.Name)
+			}
+		}
+	}
+	for _, dc := range sv.DocsList() {
+		fs := dc.FileStates.Done()
+		fs.SymsMu.RLock()
+		add(fs.Syms, "")
+		fs.SymsMu.RUnlock()
+	}
+	for _, psy := range sv.WorkspacePkgSyms() {
+		add(psy.Children, psy.Name)
+	}
+	return res, nil
+}
+
+// WorkspacePkgSyms returns the package-level symbols for all directories
+// under the RootPath, loading any that have not yet been loaded via
+// Lang.ParseDir for each supported file type in the directory.
+func (sv *Server) WorkspacePkgSyms() []*syms.Symbol {
+	if sv.RootPath == "" {
+		return nil
+	}
+	sv.PkgSymsMu.Lock()
+	defer sv.PkgSymsMu.Unlock()
+	if sv.PkgSyms == nil {
+		sv.PkgSyms = make(map[string]*syms.Symbol)
+	}
+	filepath.WalkDir(sv.RootPath, func(path string, de os.DirEntry, err error) error {
+		if err != nil || !de.IsDir() {
+			return nil
+		}
+		nm := de.Name()
+		if path != sv.RootPath && (WorkspaceExcludes[nm] || strings.HasPrefix(nm, ".") || strings.HasPrefix(nm, "_")) {
+			return filepath.SkipDir
+		}
+		for sup, lang := range DirLangs(path) {
+			key := path + ":" + sup.String()
+			if _, has := sv.PkgSyms[key]; has {
+				continue
+			}
+			sv.PkgSyms[key] = lang.ParseDir(pi.NewFileState(), path, pi.LangDirOpts{})
+		}
+		return nil
+	})
+	var psys []*syms.Symbol
+	for _, psy := range sv.PkgSyms {
+		if psy != nil {
+			psys = append(psys, psy)
+		}
+	}
+	sort.Slice(psys, func(i, j int) bool {
+		return psys[i].Filename < psys[j].Filename
+	})
+	return psys
+}
+
+// ResetPkgSyms resets the loaded package symbols for given directory
+// so they are reloaded next time they are needed
+func (sv *Server) ResetPkgSyms(dir string) {
+	sv.PkgSymsMu.Lock()
+	defer sv.PkgSymsMu.Unlock()
+	for key := range sv.PkgSyms {
+		if strings.HasPrefix(key, dir+":") {
+			delete(sv.PkgSyms, key)
+		}
+	}
+}
+
+// DirLangs returns the supported languages for the files in given directory
+func DirLangs(dir string) map[filecat.Supported]pi.Lang {
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	langs := make(map[filecat.Supported]pi.Lang)
+	for _, de := range ents {
+		if de.IsDir() {
+			continue
+		}
+		sup := filecat.ExtSupported(strings.ToLower(filepath.Ext(de.Name())))
+		if sup == filecat.NoSupport {
+			continue
+		}
+		if _, has := langs[sup]; has {
+			continue
+		}
+		if lp, err := pi.LangSupport.Props(sup); err == nil && lp.Lang != nil {
+			langs[sup] = lp.Lang
+		}
+	}
+	return langs
+}
+
+// DocsList returns the open documents as a list, sorted by uri
+func (sv *Server) DocsList() []*Document {
+	sv.DocsMu.RLock()
+	defer sv.DocsMu.RUnlock()
+	dcs := make([]*Document, 0, len(sv.Docs))
+	for _, dc := range sv.Docs {
+		dcs = append(dcs, dc)
+	}
+	sort.Slice(dcs, func(i, j int) bool {
+		return dcs[i].URI < dcs[j].URI
+	})
+	return dcs
+}
+
+// DocForFile returns the open document for given file name, if open
+func (sv *Server) DocForFile(fname string) (*Document, bool) {
+	sv.DocsMu.RLock()
+	defer sv.DocsMu.RUnlock()
+	for _, dc := range sv.Docs {
+		if SameFile(dc.Filename, fname) {
+			return dc, true
+		}
+	}
+	return nil, false
+}
+
+// RegLocation returns the Location for given region in given file,
+// using the open document for UTF-16 conversion if it is open
+func (sv *Server) RegLocation(fname string, reg lex.Reg) Location {
+	if dc, ok := sv.DocForFile(fname); ok {
+		return Location{URI: dc.URI, Range: dc.RangeFromReg(reg)}
+	}
+	rg := Range{Start: Position{Line: reg.St.Ln, Character: reg.St.Ch}, End: Position{Line: reg.Ed.Ln, Character: reg.Ed.Ch}}
+	return Location{URI: PathToURI(fname), Range: rg}
+}
+
+// SortedSyms returns the symbols in given map sorted by file and region
+func SortedSyms(sm syms.SymMap) []*syms.Symbol {
+	sys := sm.Slice(false)
+	sort.Slice(sys, func(i, j int) bool {
+		if sys[i].Filename != sys[j].Filename {
+			return sys[i].Filename < sys[j].Filename
+		}
+		if sys[i].Region.St != sys[j].Region.St {
+			return sys[i].Region.St.IsLess(sys[j].Region.St)
+		}
+		return sys[i].Name < sys[j].Name
+	})
+	return sys
+}
+
+// SameFile returns true if the two file names refer to the same file
+func SameFile(a, b string) bool {
+	if a == b {
+		return true
+	}
+	aa, err := filepath.Abs(a)
+	if err != nil {
+		return false
+	}
+	ba, err := filepath.Abs(b)
+	if err != nil {
+		return false
+	}
+	return aa == ba
+}
+
+// PosLess returns true if position a is before b
+func PosLess(a, b Position) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Character < b.Character
+}
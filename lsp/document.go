@@ -0,0 +1,206 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/goki/pi/filecat"
+	"github.com/goki/pi/lex"
+	"github.com/goki/pi/pi"
+)
+
+// Document is an open text document being managed by the Server.
+// Each document has its own pi.FileStates which hold the results
+// of processing by the pi.Lang for its supported file type.
+type Document struct {
+
+	// uri of the document as given by the client
+	URI DocumentURI `desc:"uri of the document as given by the client"`
+
+	// local file name corresponding to URI
+	Filename string `desc:"local file name corresponding to URI"`
+
+	// language id sent by the client
+	LanguageID string `desc:"language id sent by the client"`
+
+	// supported file type, determined from the filename or language id
+	Sup filecat.Supported `desc:"supported file type, determined from the filename or language id"`
+
+	// language support for this file -- nil if not supported
+	Lang pi.Lang `json:"-" xml:"-" desc:"language support for this file -- nil if not supported"`
+
+	// version number of the document, incremented by client on each change
+	Version int `desc:"version number of the document, incremented by client on each change"`
+
+	// current source text, as lines of runes
+	Lines [][]rune `json:"-" xml:"-" desc:"current source text, as lines of runes"`
+
+	// processed file states for this document
+	FileStates *pi.FileStates `json:"-" xml:"-" desc:"processed file states for this document"`
+}
+
+// NewDocument returns a new document for given item, with the language
+// support determined via pi.LangSupport.Props
+func NewDocument(item *TextDocumentItem, basepath string) *Document {
+	dc := &Document{URI: item.URI, LanguageID: item.LanguageID, Version: item.Version}
+	dc.Filename = URIToPath(item.URI)
+	dc.Sup = SupportedForDoc(dc.Filename, item.LanguageID)
+	if lp, err := pi.LangSupport.Props(dc.Sup); err == nil {
+		dc.Lang = lp.Lang
+	}
+	dc.FileStates = pi.NewFileStates(dc.Filename, basepath, dc.Sup)
+	dc.SetText(item.Text)
+	return dc
+}
+
+// SupportedForDoc returns the supported file type for given filename,
+// falling back on the client's language id if the filename is not recognized
+func SupportedForDoc(fname, langID string) filecat.Supported {
+	sup := filecat.NoSupport
+	if fname != "" {
+		sup = filecat.ExtSupported(strings.ToLower(filepath.Ext(fname)))
+		if sup == filecat.NoSupport {
+			sup = filecat.SupportedFromFile(fname)
+		}
+	}
+	if sup == filecat.NoSupport && langID != "" {
+		if lsup, err := filecat.SupportedByName(langID); err == nil {
+			sup = lsup
+		}
+	}
+	return sup
+}
+
+// SetText sets the full text of the document
+func (dc *Document) SetText(txt string) {
+	dc.Lines = lex.RunesFromString(txt)
+}
+
+// Text returns the full text of the document as bytes
+func (dc *Document) Text() []byte {
+	var sb strings.Builder
+	for i, ln := range dc.Lines {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(string(ln))
+	}
+	return []byte(sb.String())
+}
+
+// ApplyChange applies given change to the document text, returning the
+// starting line of the change, and the number of old lines replaced there
+// by the given number of new lines
+func (dc *Document) ApplyChange(ch *TextDocumentContentChangeEvent) (st, nold, nnew int) {
+	if ch.Range == nil {
+		nold = len(dc.Lines)
+		dc.SetText(ch.Text)
+		return 0, nold, len(dc.Lines)
+	}
+	sp := dc.PosFromLSP(ch.Range.Start)
+	ep := dc.PosFromLSP(ch.Range.End)
+	if ep.IsLess(sp) {
+		sp, ep = ep, sp
+	}
+	pre := string(dc.Lines[sp.Ln][:sp.Ch])
+	post := string(dc.Lines[ep.Ln][ep.Ch:])
+	nln := lex.RunesFromString(pre + ch.Text + post)
+	lns := make([][]rune, 0, len(dc.Lines)-(ep.Ln-sp.Ln)+len(nln))
+	lns = append(lns, dc.Lines[:sp.Ln]...)
+	lns = append(lns, nln...)
+	lns = append(lns, dc.Lines[ep.Ln+1:]...)
+	dc.Lines = lns
+	return sp.Ln, ep.Ln - sp.Ln + 1, len(nln)
+}
+
+// Parse runs the language ParseFile on the current text -- does nothing
+// if language is not supported
+func (dc *Document) Parse() {
+	if dc.Lang == nil {
+		return
+	}
+	dc.Lang.ParseFile(dc.FileStates, dc.Text())
+}
+
+// Reparse updates the parse for an edit that replaced nold lines starting at
+// line st with nnew lines, using the pi.Reparser interface of the language
+// if supported, and otherwise the full Parse
+func (dc *Document) Reparse(st, nold, nnew int) {
+	rp, ok := dc.Lang.(pi.Reparser)
+	if !ok {
+		dc.Parse()
+		return
+	}
+	rp.ReparseLines(dc.FileStates, dc.Text(), st, nold, nnew)
+}
+
+// PosFromLSP returns the lex.Pos (line, rune index) for given LSP Position
+// (line, UTF-16 code unit offset), clamped to the document bounds
+func (dc *Document) PosFromLSP(ps Position) lex.Pos {
+	nln := len(dc.Lines)
+	if nln == 0 {
+		return lex.PosZero
+	}
+	if ps.Line < 0 {
+		return lex.PosZero
+	}
+	if ps.Line >= nln {
+		return lex.Pos{Ln: nln - 1, Ch: len(dc.Lines[nln-1])}
+	}
+	ln := dc.Lines[ps.Line]
+	u16 := 0
+	for i, r := range ln {
+		if u16 >= ps.Character {
+			return lex.Pos{Ln: ps.Line, Ch: i}
+		}
+		u16 += utf16.RuneLen(r)
+	}
+	return lex.Pos{Ln: ps.Line, Ch: len(ln)}
+}
+
+// PosToLSP returns the LSP Position for given lex.Pos (line, rune index)
+func (dc *Document) PosToLSP(pos lex.Pos) Position {
+	if pos.Ln < 0 || pos.Ln >= len(dc.Lines) {
+		return Position{Line: pos.Ln, Character: pos.Ch}
+	}
+	ln := dc.Lines[pos.Ln]
+	ch := pos.Ch
+	if ch > len(ln) {
+		ch = len(ln)
+	}
+	u16 := 0
+	for _, r := range ln[:ch] {
+		u16 += utf16.RuneLen(r)
+	}
+	return Position{Line: pos.Ln, Character: u16}
+}
+
+// RangeFromReg returns the LSP Range for given lex.Reg source region
+func (dc *Document) RangeFromReg(reg lex.Reg) Range {
+	return Range{Start: dc.PosToLSP(reg.St), End: dc.PosToLSP(reg.Ed)}
+}
+
+// URIToPath returns the local file path for a file:// uri -- other uris
+// are returned as-is
+func URIToPath(uri DocumentURI) string {
+	u, err := url.Parse(string(uri))
+	if err != nil || u.Scheme != "file" {
+		return string(uri)
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// PathToURI returns the file:// uri for given local file path
+func PathToURI(path string) DocumentURI {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+	return DocumentURI(u.String())
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"encoding/json"
+
+	"github.com/goki/pi/pi"
+)
+
+// FoldKindNames are the LSP FoldingRange kind names for pi.FoldKinds
+var FoldKindNames = map[pi.FoldKinds]string{
+	pi.FoldRegion:  "region",
+	pi.FoldComment: "comment",
+	pi.FoldImports: "imports",
+}
+
+// FoldingRange handles the textDocument/foldingRange request, using
+// pi.LangFoldingRanges for the document language
+func (sv *Server) FoldingRange(params json.RawMessage) (interface{}, error) {
+	var fp FoldingRangeParams
+	if err := DecodeParams(params, &fp); err != nil {
+		return nil, err
+	}
+	dc, err := sv.Doc(fp.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	frs := []FoldingRange{}
+	if dc.Lang == nil {
+		return frs, nil
+	}
+	for _, fr := range pi.LangFoldingRanges(dc.Lang, dc.FileStates.Done()) {
+		rg := dc.RangeFromReg(fr.Reg)
+		frs = append(frs, FoldingRange{StartLine: rg.Start.Line, StartCharacter: rg.Start.Character, EndLine: rg.End.Line, EndCharacter: rg.End.Character, Kind: FoldKindNames[fr.Kind]})
+	}
+	return frs, nil
+}
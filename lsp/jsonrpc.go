@@ -0,0 +1,145 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// JSON-RPC 2.0 error codes, including those reserved by LSP
+const (
+	ParseError           = -32700
+	InvalidRequest       = -32600
+	MethodNotFound       = -32601
+	InvalidParams        = -32602
+	InternalError        = -32603
+	ServerNotInitialized = -32002
+	RequestFailed        = -32803
+)
+
+// Message is a generic JSON-RPC 2.0 message as read from the client.
+// If Method is set it is a request (ID non-nil) or notification (ID nil),
+// otherwise it is a response to a request sent by the server.
+type Message struct {
+
+	// protocol version -- always 2.0
+	JSONRPC string `json:"jsonrpc" desc:"protocol version -- always 2.0"`
+
+	// request id -- number or string -- nil for notifications
+	ID *json.RawMessage `json:"id,omitempty" desc:"request id -- number or string -- nil for notifications"`
+
+	// method being invoked
+	Method string `json:"method,omitempty" desc:"method being invoked"`
+
+	// parameters for the method, decoded by the handler
+	Params json.RawMessage `json:"params,omitempty" desc:"parameters for the method, decoded by the handler"`
+
+	// result, for responses
+	Result json.RawMessage `json:"result,omitempty" desc:"result, for responses"`
+
+	// error, for responses
+	Error *RespError `json:"error,omitempty" desc:"error, for responses"`
+}
+
+// IsNotify returns true if this message is a notification (no id)
+func (ms *Message) IsNotify() bool {
+	return ms.ID == nil
+}
+
+// Response is a JSON-RPC 2.0 response sent back to the client
+type Response struct {
+
+	// protocol version -- always 2.0
+	JSONRPC string `json:"jsonrpc" desc:"protocol version -- always 2.0"`
+
+	// id of the request this is responding to
+	ID *json.RawMessage `json:"id" desc:"id of the request this is responding to"`
+
+	// result of the request -- always present (possibly null) when no error
+	Result interface{} `json:"result" desc:"result of the request -- always present (possibly null) when no error"`
+
+	// error, if request failed
+	Error *RespError `json:"error,omitempty" desc:"error, if request failed"`
+}
+
+// Notification is a JSON-RPC 2.0 notification sent to the client,
+// e.g., textDocument/publishDiagnostics
+type Notification struct {
+
+	// protocol version -- always 2.0
+	JSONRPC string `json:"jsonrpc" desc:"protocol version -- always 2.0"`
+
+	// method being invoked
+	Method string `json:"method" desc:"method being invoked"`
+
+	// parameters for the method
+	Params interface{} `json:"params,omitempty" desc:"parameters for the method"`
+}
+
+// RespError is a JSON-RPC 2.0 error object
+type RespError struct {
+
+	// error code -- see ParseError etc
+	Code int `json:"code" desc:"error code -- see ParseError etc"`
+
+	// error message
+	Message string `json:"message" desc:"error message"`
+}
+
+// Error satisfies the error interface
+func (re *RespError) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", re.Code, re.Message)
+}
+
+// NewRespError returns a new RespError with given code and formatted message
+func NewRespError(code int, format string, args ...interface{}) *RespError {
+	return &RespError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// ReadMessage reads the next base-protocol framed message from given reader:
+// a header section with Content-Length, a blank line, and then the JSON content.
+func ReadMessage(r *bufio.Reader) (*Message, error) {
+	tp := textproto.NewReader(r)
+	hdr, err := tp.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	cls := strings.TrimSpace(hdr.Get("Content-Length"))
+	if cls == "" {
+		return nil, fmt.Errorf("lsp.ReadMessage: missing Content-Length header")
+	}
+	cl, err := strconv.Atoi(cls)
+	if err != nil {
+		return nil, fmt.Errorf("lsp.ReadMessage: bad Content-Length: %v", cls)
+	}
+	buf := make([]byte, cl)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	ms := &Message{}
+	if err := json.Unmarshal(buf, ms); err != nil {
+		return nil, NewRespError(ParseError, "%v", err)
+	}
+	return ms, nil
+}
+
+// WriteMessage writes given value as a base-protocol framed JSON message
+func WriteMessage(w io.Writer, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(b)); err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
@@ -0,0 +1,276 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	"github.com/goki/pi/complete"
+	"github.com/goki/pi/lex"
+	"github.com/goki/pi/pi"
+	"github.com/goki/pi/syms"
+	"github.com/goki/pi/token"
+)
+
+// HoverMaxLines is the maximum number of source lines shown in hover
+// when the lookup result is a file region
+var HoverMaxLines = 12
+
+// Lookup calls Lang.Lookup for the word at given position, returning
+// the lookup result and the region of the word.  The text passed to
+// Lookup is the line up to the end of the word, as in the GUI.
+func (dc *Document) Lookup(pos lex.Pos) (complete.Lookup, lex.Reg, bool) {
+	var ld complete.Lookup
+	if dc.Lang == nil {
+		return ld, lex.RegZero, false
+	}
+	reg, ok := dc.WordAt(pos)
+	if !ok {
+		return ld, lex.RegZero, false
+	}
+	text := strings.TrimLeft(string(dc.Lines[reg.Ed.Ln][:reg.Ed.Ch]), " \t")
+	ld = dc.Lang.Lookup(dc.FileStates, text, reg.Ed)
+	return ld, reg, true
+}
+
+// Definition handles the textDocument/definition request, returning the
+// Location of the Lang.Lookup result if it is a file, and null otherwise
+func (sv *Server) Definition(params json.RawMessage) (interface{}, error) {
+	var tp TextDocumentPositionParams
+	if err := DecodeParams(params, &tp); err != nil {
+		return nil, err
+	}
+	dc, err := sv.Doc(tp.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	ld, reg, ok := dc.Lookup(dc.PosFromLSP(tp.Position))
+	if !ok || ld.Filename == "" {
+		return nil, nil
+	}
+	loc := sv.LookupLocation(&ld, dc.RegSrc(reg))
+	return &loc, nil
+}
+
+// LookupLocation returns the location for given file lookup result,
+// selecting the first instance of given name on the starting line
+// if found, and otherwise the starting line.
+func (sv *Server) LookupLocation(ld *complete.Lookup, name string) Location {
+	lns := sv.FileLines(ld.Filename)
+	reg := lex.Reg{St: lex.Pos{Ln: ld.StLine}, Ed: lex.Pos{Ln: ld.StLine}}
+	if ld.StLine >= 0 && ld.StLine < len(lns) {
+		ln := string(lns[ld.StLine])
+		reg.Ed.Ch = len(lns[ld.StLine])
+		if bi := strings.Index(ln, name); bi >= 0 && name != "" {
+			reg.St.Ch = len([]rune(ln[:bi]))
+			reg.Ed.Ch = reg.St.Ch + len([]rune(name))
+		}
+	}
+	return sv.RegLocation(ld.Filename, reg)
+}
+
+// FileLines returns the source lines for given file, from the open
+// document if it is open, and otherwise from the file
+func (sv *Server) FileLines(fname string) [][]rune {
+	if dc, ok := sv.DocForFile(fname); ok {
+		return dc.Lines
+	}
+	b, err := lex.OpenFileBytes(fname)
+	if err != nil {
+		return nil
+	}
+	return lex.RunesFromBytes(b)
+}
+
+// Hover handles the textDocument/hover request, returning markdown with the
+// symbol at the position (with its Detail / Type), followed by the Lang.Lookup
+// Text if set, or the source at the looked-up file region
+func (sv *Server) Hover(params json.RawMessage) (interface{}, error) {
+	var tp TextDocumentPositionParams
+	if err := DecodeParams(params, &tp); err != nil {
+		return nil, err
+	}
+	dc, err := sv.Doc(tp.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	pos := dc.PosFromLSP(tp.Position)
+	ld, reg, ok := dc.Lookup(pos)
+	if !ok {
+		return nil, nil
+	}
+	var sb strings.Builder
+	lang := dc.CodeLang()
+	fs := dc.FileStates.Done()
+	fs.SymsMu.RLock()
+	sy := dc.SymbolAt(fs, reg, &ld)
+	if sy != nil {
+		sb.WriteString("```" + lang + "\n" + SymbolSig(sy) + "\n```\n")
+	}
+	fs.SymsMu.RUnlock()
+	switch {
+	case len(ld.Text) > 0:
+		sb.WriteString("\n" + string(ld.Text) + "\n")
+	case ld.Filename != "":
+		lns := sv.FileLines(ld.Filename)
+		st, ed := ld.StLine, ld.EdLine
+		if ed < st {
+			ed = st
+		}
+		if ed-st >= HoverMaxLines {
+			ed = st + HoverMaxLines - 1
+		}
+		if st >= 0 && st < len(lns) {
+			if ed >= len(lns) {
+				ed = len(lns) - 1
+			}
+			sb.WriteString("\n```" + lang + "\n")
+			for ln := st; ln <= ed; ln++ {
+				sb.WriteString(string(lns[ln]) + "\n")
+			}
+			sb.WriteString("```\n")
+		}
+	}
+	if sb.Len() == 0 {
+		return nil, nil
+	}
+	rg := dc.RangeFromReg(reg)
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: sb.String()}, Range: &rg}, nil
+}
+
+// SymbolSig returns a signature for given symbol, following Symbol.Label:
+// functions show the args and returns from the Detail, types show their
+// definition (Detail, with |> line breaks), and others show their Type
+func SymbolSig(sy *syms.Symbol) string {
+	switch {
+	case sy.Kind.SubCat() == token.NameFunction:
+		if pi := strings.Index(sy.Detail, "("); pi >= 0 {
+			return "func " + sy.Name + sy.Detail[pi:]
+		}
+		return "func " + sy.Name + "()"
+	case sy.Kind == token.NameType && sy.Detail != "":
+		return "type " + sy.Name + " " + strings.ReplaceAll(sy.Detail, "|>", "\n")
+	case sy.Type != "" && sy.Type != sy.Name && sy.Type != "<err>":
+		return sy.Name + " " + sy.Type
+	}
+	return sy.Name
+}
+
+// SymbolAt returns the symbol for the name at given region, preferring the
+// symbol defined at the lookup location if it is a file, and otherwise
+// resolving the name through the package qualifier or the scopes
+// containing the region.  Must be called under SymsMu read lock.
+func (dc *Document) SymbolAt(fs *pi.FileState, reg lex.Reg, ld *complete.Lookup) *syms.Symbol {
+	nm := dc.RegSrc(reg)
+	if nm == "" {
+		return nil
+	}
+	if ld != nil && ld.Filename != "" {
+		if sy := FindSymDef(fs.Syms, ld.Filename, ld.StLine, nm); sy != nil {
+			return sy
+		}
+		if sy := FindSymDef(fs.ExtSyms, ld.Filename, ld.StLine, nm); sy != nil {
+			return sy
+		}
+	}
+	if qual := dc.Qualifier(reg); qual != "" {
+		if psy, has := fs.ExtSyms[qual]; has {
+			if sy, has := psy.Children[nm]; has {
+				return sy
+			}
+		}
+	}
+	fpath, _ := filepath.Abs(fs.Src.Filename)
+	var scopes syms.SymMap
+	fs.Syms.FindContainsRegion(fpath, reg.St, 2, token.None, &scopes)
+	if sy, has := fs.FindNameScoped(nm, scopes); has {
+		return sy
+	}
+	return nil
+}
+
+// FindSymDef finds the symbol with given name defined in given file starting on
+// given line, searching recursively through the symbol map
+func FindSymDef(sm syms.SymMap, fname string, ln int, nm string) *syms.Symbol {
+	for _, sy := range sm {
+		if sy.Name == nm && sy.Region.St.Ln == ln && SameFile(sy.Filename, fname) {
+			return sy
+		}
+		if len(sy.Children) > 0 {
+			if csy := FindSymDef(sy.Children, fname, ln, nm); csy != nil {
+				return csy
+			}
+		}
+	}
+	return nil
+}
+
+// IsWordRune returns true if rune is part of an identifier word
+func IsWordRune(r rune) bool {
+	return r == '_' || lex.IsLetterOrDigit(r)
+}
+
+// WordAt returns the region of the identifier word at or just before given position
+func (dc *Document) WordAt(pos lex.Pos) (lex.Reg, bool) {
+	if pos.Ln < 0 || pos.Ln >= len(dc.Lines) {
+		return lex.RegZero, false
+	}
+	ln := dc.Lines[pos.Ln]
+	ch := pos.Ch
+	if ch > len(ln) {
+		ch = len(ln)
+	}
+	if (ch == len(ln) || !IsWordRune(ln[ch])) && ch > 0 && IsWordRune(ln[ch-1]) {
+		ch--
+	}
+	if ch >= len(ln) || !IsWordRune(ln[ch]) {
+		return lex.RegZero, false
+	}
+	st := ch
+	for st > 0 && IsWordRune(ln[st-1]) {
+		st--
+	}
+	ed := ch
+	for ed < len(ln) && IsWordRune(ln[ed]) {
+		ed++
+	}
+	return lex.Reg{St: lex.Pos{Ln: pos.Ln, Ch: st}, Ed: lex.Pos{Ln: pos.Ln, Ch: ed}}, true
+}
+
+// Qualifier returns the word before a . preceding given word region, if any
+// e.g., the package name in a qualified name
+func (dc *Document) Qualifier(reg lex.Reg) string {
+	ln := dc.Lines[reg.St.Ln]
+	if reg.St.Ch < 2 || ln[reg.St.Ch-1] != '.' {
+		return ""
+	}
+	qreg, ok := dc.WordAt(lex.Pos{Ln: reg.St.Ln, Ch: reg.St.Ch - 1})
+	if !ok {
+		return ""
+	}
+	return dc.RegSrc(qreg)
+}
+
+// RegSrc returns the source for given single-line region
+func (dc *Document) RegSrc(reg lex.Reg) string {
+	if reg.St.Ln < 0 || reg.St.Ln >= len(dc.Lines) || reg.Ed.Ln != reg.St.Ln {
+		return ""
+	}
+	ln := dc.Lines[reg.St.Ln]
+	if reg.St.Ch < 0 || reg.Ed.Ch > len(ln) || reg.Ed.Ch < reg.St.Ch {
+		return ""
+	}
+	return string(ln[reg.St.Ch:reg.Ed.Ch])
+}
+
+// CodeLang returns the language name to use for markdown code blocks
+func (dc *Document) CodeLang() string {
+	if dc.LanguageID != "" {
+		return dc.LanguageID
+	}
+	return strings.ToLower(dc.Sup.String())
+}
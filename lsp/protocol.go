@@ -0,0 +1,363 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+// This file has the subset of the LSP protocol data structures that we
+// support, with json names matching the spec.
+
+// DocumentURI is a URI for a document, typically file://
+type DocumentURI string
+
+// Position is a zero-based line and character offset in a document,
+// where the character offset is in UTF-16 code units per the spec.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a range in a document, with End exclusive
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location is a range within a given document
+type Location struct {
+	URI   DocumentURI `json:"uri"`
+	Range Range       `json:"range"`
+}
+
+// TextDocumentIdentifier identifies a document
+type TextDocumentIdentifier struct {
+	URI DocumentURI `json:"uri"`
+}
+
+// VersionedTextDocumentIdentifier identifies a specific version of a document
+type VersionedTextDocumentIdentifier struct {
+	URI     DocumentURI `json:"uri"`
+	Version int         `json:"version"`
+}
+
+// TextDocumentItem is the full document info sent on didOpen
+type TextDocumentItem struct {
+	URI        DocumentURI `json:"uri"`
+	LanguageID string      `json:"languageId"`
+	Version    int         `json:"version"`
+	Text       string      `json:"text"`
+}
+
+// TextDocumentPositionParams is the common set of params for position-based requests
+type TextDocumentPositionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+// TextEdit is a replacement of given range with new text
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//  Lifecycle
+
+// InitializeParams are the params of the initialize request -- we only use
+// a few of the fields
+type InitializeParams struct {
+	ProcessID int         `json:"processId"`
+	RootURI   DocumentURI `json:"rootUri"`
+	RootPath  string      `json:"rootPath"`
+}
+
+// TextDocumentSyncKind determines how the client sends document changes
+type TextDocumentSyncKind int
+
+const (
+	SyncNone TextDocumentSyncKind = iota
+	SyncFull
+	SyncIncremental
+)
+
+// TextDocumentSyncOptions specifies how documents are synced
+type TextDocumentSyncOptions struct {
+	OpenClose bool                 `json:"openClose"`
+	Change    TextDocumentSyncKind `json:"change"`
+}
+
+// ServerCapabilities are the capabilities reported back on initialize
+type ServerCapabilities struct {
+	TextDocumentSync        TextDocumentSyncOptions `json:"textDocumentSync"`
+	CompletionProvider      *CompletionOptions      `json:"completionProvider,omitempty"`
+	DocumentSymbolProvider  bool                    `json:"documentSymbolProvider,omitempty"`
+	WorkspaceSymbolProvider bool                    `json:"workspaceSymbolProvider,omitempty"`
+	DefinitionProvider      bool                    `json:"definitionProvider,omitempty"`
+	HoverProvider           bool                    `json:"hoverProvider,omitempty"`
+	RenameProvider          *RenameOptions          `json:"renameProvider,omitempty"`
+	SignatureHelpProvider   *SignatureHelpOptions   `json:"signatureHelpProvider,omitempty"`
+	FoldingRangeProvider    bool                    `json:"foldingRangeProvider,omitempty"`
+	SemanticTokensProvider  *SemanticTokensOptions  `json:"semanticTokensProvider,omitempty"`
+}
+
+// ServerInfo identifies the server
+type ServerInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// InitializeResult is the result of the initialize request
+type InitializeResult struct {
+	Capabilities ServerCapabilities `json:"capabilities"`
+	ServerInfo   ServerInfo         `json:"serverInfo"`
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//  Document sync
+
+// DidOpenTextDocumentParams are the params for textDocument/didOpen
+type DidOpenTextDocumentParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+// TextDocumentContentChangeEvent is a change to a document -- if Range is nil
+// then Text is the full new content of the document
+type TextDocumentContentChangeEvent struct {
+	Range *Range `json:"range,omitempty"`
+	Text  string `json:"text"`
+}
+
+// DidChangeTextDocumentParams are the params for textDocument/didChange
+type DidChangeTextDocumentParams struct {
+	TextDocument   VersionedTextDocumentIdentifier  `json:"textDocument"`
+	ContentChanges []TextDocumentContentChangeEvent `json:"contentChanges"`
+}
+
+// DidSaveTextDocumentParams are the params for textDocument/didSave
+type DidSaveTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// DidCloseTextDocumentParams are the params for textDocument/didClose
+type DidCloseTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//  Completion
+
+// CompletionOptions are the server options for completion
+type CompletionOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters,omitempty"`
+	ResolveProvider   bool     `json:"resolveProvider"`
+}
+
+// CompletionParams are the params for textDocument/completion
+type CompletionParams struct {
+	TextDocumentPositionParams
+}
+
+// InsertTextFormat determines whether the insert text is plain or a snippet
+type InsertTextFormat int
+
+const (
+	PlainTextFormat InsertTextFormat = 1
+	SnippetFormat   InsertTextFormat = 2
+)
+
+// CompletionItem is one completion -- Kind is an int CompletionKind,
+// as the CompletionKind type marshals as a string
+type CompletionItem struct {
+	Label            string           `json:"label"`
+	Kind             int              `json:"kind,omitempty"`
+	Detail           string           `json:"detail,omitempty"`
+	SortText         string           `json:"sortText,omitempty"`
+	FilterText       string           `json:"filterText,omitempty"`
+	InsertTextFormat InsertTextFormat `json:"insertTextFormat,omitempty"`
+	TextEdit         *TextEdit        `json:"textEdit,omitempty"`
+}
+
+// CompletionList is the result of textDocument/completion
+type CompletionList struct {
+	IsIncomplete bool             `json:"isIncomplete"`
+	Items        []CompletionItem `json:"items"`
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//  Symbols
+
+// DocumentSymbolParams are the params for textDocument/documentSymbol
+type DocumentSymbolParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// DocumentSymbol is one symbol in the hierarchical outline of a document --
+// Kind is an int SymbolKind, as the SymbolKind type marshals as a string
+type DocumentSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail,omitempty"`
+	Kind           int              `json:"kind"`
+	Range          Range            `json:"range"`
+	SelectionRange Range            `json:"selectionRange"`
+	Children       []DocumentSymbol `json:"children,omitempty"`
+}
+
+// WorkspaceSymbolParams are the params for workspace/symbol
+type WorkspaceSymbolParams struct {
+	Query string `json:"query"`
+}
+
+// SymbolInformation is one symbol found in the workspace
+type SymbolInformation struct {
+	Name          string   `json:"name"`
+	Kind          int      `json:"kind"`
+	Location      Location `json:"location"`
+	ContainerName string   `json:"containerName,omitempty"`
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//  Diagnostics
+
+// DiagnosticSeverity is the severity of a diagnostic
+type DiagnosticSeverity int
+
+const (
+	SevError DiagnosticSeverity = iota + 1
+	SevWarning
+	SevInformation
+	SevHint
+)
+
+// Diagnostic is an error or warning about a range in a document
+type Diagnostic struct {
+	Range    Range              `json:"range"`
+	Severity DiagnosticSeverity `json:"severity,omitempty"`
+	Code     string             `json:"code,omitempty"`
+	Source   string             `json:"source,omitempty"`
+	Message  string             `json:"message"`
+}
+
+// PublishDiagnosticsParams are the params for the textDocument/publishDiagnostics notification
+type PublishDiagnosticsParams struct {
+	URI         DocumentURI  `json:"uri"`
+	Version     int          `json:"version,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//  Lookup
+
+// MarkupContent is text in a given format -- we always use markdown
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// Hover is the result of textDocument/hover
+type Hover struct {
+	Contents MarkupContent `json:"contents"`
+	Range    *Range        `json:"range,omitempty"`
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//  Rename
+
+// RenameOptions are the server options for rename
+type RenameOptions struct {
+	PrepareProvider bool `json:"prepareProvider"`
+}
+
+// RenameParams are the params for textDocument/rename
+type RenameParams struct {
+	TextDocumentPositionParams
+	NewName string `json:"newName"`
+}
+
+// PrepareRenameResult is the result of textDocument/prepareRename
+type PrepareRenameResult struct {
+	Range       Range  `json:"range"`
+	Placeholder string `json:"placeholder"`
+}
+
+// WorkspaceEdit is a set of edits to documents, by document
+type WorkspaceEdit struct {
+	Changes map[DocumentURI][]TextEdit `json:"changes"`
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//  SignatureHelp
+
+// SignatureHelpOptions are the server options for signature help
+type SignatureHelpOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters,omitempty"`
+}
+
+// ParameterInformation is one parameter of a signature
+type ParameterInformation struct {
+	Label string `json:"label"`
+}
+
+// SignatureInformation is the signature of a function being called
+type SignatureInformation struct {
+	Label         string                 `json:"label"`
+	Documentation string                 `json:"documentation,omitempty"`
+	Parameters    []ParameterInformation `json:"parameters"`
+}
+
+// SignatureHelp is the result of textDocument/signatureHelp
+type SignatureHelp struct {
+	Signatures      []SignatureInformation `json:"signatures"`
+	ActiveSignature int                    `json:"activeSignature"`
+	ActiveParameter int                    `json:"activeParameter"`
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//  FoldingRange
+
+// FoldingRangeParams are the params for textDocument/foldingRange
+type FoldingRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// FoldingRange is a foldable region of a document.  Kind is one of
+// comment, imports or region
+type FoldingRange struct {
+	StartLine      int    `json:"startLine"`
+	StartCharacter int    `json:"startCharacter"`
+	EndLine        int    `json:"endLine"`
+	EndCharacter   int    `json:"endCharacter"`
+	Kind           string `json:"kind,omitempty"`
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//  SemanticTokens
+
+// SemanticTokensLegend lists the token types and modifiers, by index in the encoded data
+type SemanticTokensLegend struct {
+	TokenTypes     []string `json:"tokenTypes"`
+	TokenModifiers []string `json:"tokenModifiers"`
+}
+
+// SemanticTokensOptions are the server options for semantic tokens
+type SemanticTokensOptions struct {
+	Legend SemanticTokensLegend `json:"legend"`
+	Range  bool                 `json:"range"`
+	Full   bool                 `json:"full"`
+}
+
+// SemanticTokensParams are the params for textDocument/semanticTokens/full
+type SemanticTokensParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// SemanticTokensRangeParams are the params for textDocument/semanticTokens/range
+type SemanticTokensRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+}
+
+// SemanticTokens is the result of semantic tokens requests, with five
+// numbers per token: delta line, delta start character, length, type, modifiers
+type SemanticTokens struct {
+	Data []uint32 `json:"data"`
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"encoding/json"
+
+	"github.com/goki/pi/pi"
+)
+
+// Renamer returns the pi.Renamer for the document language, or an
+// error if the language does not support renaming
+func (dc *Document) Renamer() (pi.Renamer, error) {
+	rn, ok := dc.Lang.(pi.Renamer)
+	if !ok {
+		return nil, NewRespError(RequestFailed, "rename is not supported for: %v", dc.Sup)
+	}
+	return rn, nil
+}
+
+// PrepareRename handles the textDocument/prepareRename request, returning
+// the range of the name at the position, or an error if it cannot be renamed
+func (sv *Server) PrepareRename(params json.RawMessage) (interface{}, error) {
+	var tp TextDocumentPositionParams
+	if err := DecodeParams(params, &tp); err != nil {
+		return nil, err
+	}
+	dc, err := sv.Doc(tp.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	rn, err := dc.Renamer()
+	if err != nil {
+		return nil, err
+	}
+	reg, err := rn.PrepareRename(dc.FileStates, dc.PosFromLSP(tp.Position))
+	if err != nil {
+		return nil, NewRespError(RequestFailed, "%v", err)
+	}
+	return &PrepareRenameResult{Range: dc.RangeFromReg(reg), Placeholder: dc.RegSrc(reg)}, nil
+}
+
+// Rename handles the textDocument/rename request, returning the edits to
+// all the files that refer to the symbol at the position, or an error if
+// it cannot be renamed (e.g., the new name clashes with an existing symbol)
+func (sv *Server) Rename(params json.RawMessage) (interface{}, error) {
+	var rp RenameParams
+	if err := DecodeParams(params, &rp); err != nil {
+		return nil, err
+	}
+	dc, err := sv.Doc(rp.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	rn, err := dc.Renamer()
+	if err != nil {
+		return nil, err
+	}
+	eds, err := rn.Rename(dc.FileStates, dc.PosFromLSP(rp.Position), rp.NewName)
+	if err != nil {
+		return nil, NewRespError(RequestFailed, "%v", err)
+	}
+	we := &WorkspaceEdit{Changes: make(map[DocumentURI][]TextEdit)}
+	for _, ed := range eds {
+		loc := sv.RegLocation(ed.Filename, ed.Reg)
+		we.Changes[loc.URI] = append(we.Changes[loc.URI], TextEdit{Range: loc.Range, NewText: ed.Text})
+	}
+	return we, nil
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"encoding/json"
+
+	"github.com/goki/pi/lex"
+	"github.com/goki/pi/token"
+)
+
+// SemanticTokenTypes is the legend of LSP semantic token types,
+// in the order of their index in the encoded token data
+var SemanticTokenTypes = []string{"namespace", "type", "class", "enum", "interface", "struct", "typeParameter", "parameter", "variable", "property", "enumMember", "event", "function", "method", "macro", "keyword", "modifier", "comment", "string", "number", "regexp", "operator"}
+
+// SemanticTokenModifiers is the legend of LSP semantic token modifiers,
+// in the order of their bit in the encoded token data
+var SemanticTokenModifiers = []string{"readonly", "defaultLibrary"}
+
+// CatSemanticTypeMap maps each token category (token.Cats) to its LSP
+// semantic token type, which is used for any token that does not have a more
+// specific type in TokenSemanticTypeMap or SubCatSemanticTypeMap, so that
+// every token, including new ones, gets a type from its category.
+// An empty type means the tokens are not reported, leaving them to the
+// syntax highlighting of the client: None has the zero-width markers
+// such as EOS, and there are no LSP types for punctuation or plain text
+// (which also has the zero-width IndentBlock and DedentBlock).
+var CatSemanticTypeMap = map[token.Tokens]string{
+	token.None:        "",
+	token.Keyword:     "keyword",
+	token.Name:        "variable",
+	token.Literal:     "string",
+	token.Operator:    "operator",
+	token.Punctuation: "",
+	token.Comment:     "comment",
+	token.Text:        "",
+}
+
+// SubCatSemanticTypeMap maps token sub-categories (token.SubCats) to their
+// LSP semantic token type, where it differs from that of their category
+var SubCatSemanticTypeMap = map[token.Tokens]string{
+	token.NameType:     "type",
+	token.NameFunction: "function",
+	token.NameScope:    "namespace",
+	token.NameValue:    "property",
+	token.LitNum:       "number",
+}
+
+// TokenSemanticTypeMap maps individual tokens to their LSP semantic token
+// type, only where it is more specific than that of their sub-category
+var TokenSemanticTypeMap = map[token.Tokens]string{
+	token.KeywordType:    "type",
+	token.NameClass:      "class",
+	token.NameStruct:     "struct",
+	token.NameField:      "property",
+	token.NameInterface:  "interface",
+	token.NameConstant:   "variable",
+	token.NameEnum:       "enum",
+	token.NameEnumMember: "enumMember",
+	token.NameTypeParam:  "typeParameter",
+	token.NameDecorator:  "macro",
+	token.NameMethod:     "method",
+	token.NameOperator:   "operator",
+	token.NameEvent:      "event",
+	token.NameVarParam:   "parameter",
+	token.LiteralBool:    "keyword",
+	token.LitStrRegex:    "regexp",
+	token.OperatorWord:   "keyword",
+}
+
+// TokenSemanticModsMap maps from tokens to LSP semantic token modifiers
+var TokenSemanticModsMap = map[token.Tokens][]string{
+	token.KeywordConstant:   {"readonly"},
+	token.KeywordType:       {"defaultLibrary"},
+	token.NameConstant:      {"readonly"},
+	token.NameEnumMember:    {"readonly"},
+	token.NameBuiltin:       {"defaultLibrary"},
+	token.NameBuiltinPseudo: {"defaultLibrary"},
+}
+
+// SemanticTokenTypeName returns the LSP semantic token type for given token,
+// from TokenSemanticTypeMap, or else its sub-category in SubCatSemanticTypeMap,
+// or else its category in CatSemanticTypeMap -- empty if it is not reported
+func SemanticTokenTypeName(tk token.Tokens) string {
+	if st, ok := TokenSemanticTypeMap[tk]; ok {
+		return st
+	}
+	if st, ok := SubCatSemanticTypeMap[tk.SubCat()]; ok {
+		return st
+	}
+	return CatSemanticTypeMap[tk.Cat()]
+}
+
+// SemanticTokenType returns the index in SemanticTokenTypes for given
+// token, from SemanticTokenTypeName, and -1 if it is not reported as a
+// semantic token
+func SemanticTokenType(tk token.Tokens) int {
+	st := SemanticTokenTypeName(tk)
+	if st == "" {
+		return -1
+	}
+	for i, nm := range SemanticTokenTypes {
+		if nm == st {
+			return i
+		}
+	}
+	return -1
+}
+
+// SemanticTokenMods returns the bit flags for the SemanticTokenModifiers of given token
+func SemanticTokenMods(tk token.Tokens) uint32 {
+	var mods uint32
+	for _, md := range TokenSemanticModsMap[tk] {
+		for i, nm := range SemanticTokenModifiers {
+			if nm == md {
+				mods |= 1 << uint(i)
+			}
+		}
+	}
+	return mods
+}
+
+// SemanticTokens returns the LSP encoding of the lexed tokens, including
+// comments, that start within given region of the file: five numbers per
+// token, with the line and starting character relative to the previous token,
+// the length, and the indexes of the type and modifiers in the legend.
+// This reflects the tokens as upgraded by the parser (e.g., to NameFunction).
+func (dc *Document) SemanticTokens(src *lex.File, reg lex.Reg) []uint32 {
+	data := []uint32{}
+	var prv Position
+	for ln := reg.St.Ln; ln <= reg.Ed.Ln && ln < src.NLines() && ln < len(dc.Lines); ln++ {
+		if ln < 0 {
+			continue
+		}
+		for _, lx := range src.LexLine(ln) {
+			if lx.Ed <= lx.St {
+				continue // e.g., EOS
+			}
+			if (ln == reg.St.Ln && lx.St < reg.St.Ch) || (ln == reg.Ed.Ln && lx.St >= reg.Ed.Ch) {
+				continue
+			}
+			ty := SemanticTokenType(lx.Tok.Tok)
+			if ty < 0 {
+				continue
+			}
+			st := dc.PosToLSP(lex.Pos{Ln: ln, Ch: lx.St})
+			ed := dc.PosToLSP(lex.Pos{Ln: ln, Ch: lx.Ed})
+			dch := st.Character
+			if st.Line == prv.Line {
+				dch -= prv.Character
+			}
+			data = append(data, uint32(st.Line-prv.Line), uint32(dch), uint32(ed.Character-st.Character), uint32(ty), SemanticTokenMods(lx.Tok.Tok))
+			prv = st
+		}
+	}
+	return data
+}
+
+// SemanticTokensFull handles the textDocument/semanticTokens/full request
+func (sv *Server) SemanticTokensFull(params json.RawMessage) (interface{}, error) {
+	var sp SemanticTokensParams
+	if err := DecodeParams(params, &sp); err != nil {
+		return nil, err
+	}
+	dc, err := sv.Doc(sp.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	fs := dc.FileStates.Done()
+	nln := len(dc.Lines)
+	return &SemanticTokens{Data: dc.SemanticTokens(&fs.Src, lex.Reg{Ed: lex.Pos{Ln: nln}})}, nil
+}
+
+// SemanticTokensRange handles the textDocument/semanticTokens/range request
+func (sv *Server) SemanticTokensRange(params json.RawMessage) (interface{}, error) {
+	var sp SemanticTokensRangeParams
+	if err := DecodeParams(params, &sp); err != nil {
+		return nil, err
+	}
+	dc, err := sv.Doc(sp.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	fs := dc.FileStates.Done()
+	reg := lex.Reg{St: dc.PosFromLSP(sp.Range.Start), Ed: dc.PosFromLSP(sp.Range.End)}
+	return &SemanticTokens{Data: dc.SemanticTokens(&fs.Src, reg)}, nil
+}
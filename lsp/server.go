@@ -0,0 +1,358 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"sync"
+
+	"github.com/goki/pi/pi"
+	"github.com/goki/pi/syms"
+)
+
+// Handler handles a given LSP method, decoding params as appropriate,
+// and returning the result (ignored for notifications) or an error.
+// Errors of type *RespError are returned to the client as-is.
+type Handler func(sv *Server, params json.RawMessage) (interface{}, error)
+
+// Server is a Language Server Protocol server communicating via JSON-RPC 2.0
+// over a reader and writer (typically stdin / stdout).  Each open document
+// is routed to the pi.Lang for its file type, as given by pi.LangSupport.Props,
+// and keeps its own pi.FileStates with the processed results.
+// Messages are processed sequentially in the order received.
+type Server struct {
+
+	// input stream from the client
+	In *bufio.Reader `json:"-" xml:"-" desc:"input stream from the client"`
+
+	// output stream to the client
+	Out io.Writer `json:"-" xml:"-" desc:"output stream to the client"`
+
+	// root path of the workspace, from initialize
+	RootPath string `desc:"root path of the workspace, from initialize"`
+
+	// open documents, keyed by uri
+	Docs map[DocumentURI]*Document `desc:"open documents, keyed by uri"`
+
+	// handlers for each method
+	Handlers map[string]Handler `json:"-" xml:"-" desc:"handlers for each method"`
+
+	// true when initialize has been received
+	Initialized bool `desc:"true when initialize has been received"`
+
+	// true when shutdown has been received -- only exit is valid after
+	Shutdown bool `desc:"true when shutdown has been received -- only exit is valid after"`
+
+	// exit code to use when exiting -- 0 if shutdown was received before exit, else 1
+	ExitCode int `desc:"exit code to use when exiting -- 0 if shutdown was received before exit, else 1"`
+
+	// log each message received and sent to the log
+	Trace bool `desc:"log each message received and sent to the log"`
+
+	// package-level symbols for each directory in the workspace, as loaded by Lang.ParseDir, keyed by path and file type
+	PkgSyms map[string]*syms.Symbol `json:"-" xml:"-" desc:"package-level symbols for each directory in the workspace, as loaded by Lang.ParseDir, keyed by path and file type"`
+
+	// mutex protecting Docs
+	DocsMu sync.RWMutex `json:"-" xml:"-" view:"-" desc:"mutex protecting Docs"`
+
+	// mutex protecting PkgSyms
+	PkgSymsMu sync.Mutex `json:"-" xml:"-" view:"-" desc:"mutex protecting PkgSyms"`
+
+	// mutex protecting writes to Out
+	OutMu sync.Mutex `json:"-" xml:"-" view:"-" desc:"mutex protecting writes to Out"`
+}
+
+// NewServer returns a new server reading from in and writing to out,
+// with the standard handlers installed.  pi.LangSupport.OpenStd() should
+// have been called, and the languages imported (e.g., via suplangs).
+func NewServer(in io.Reader, out io.Writer) *Server {
+	sv := &Server{In: bufio.NewReader(in), Out: out, ExitCode: 1}
+	sv.Docs = make(map[DocumentURI]*Document)
+	sv.InitHandlers()
+	return sv
+}
+
+// InitHandlers installs the standard set of method handlers
+func (sv *Server) InitHandlers() {
+	sv.Handlers = map[string]Handler{
+		"initialize":                        (*Server).Initialize,
+		"initialized":                       (*Server).Nop,
+		"shutdown":                          (*Server).ShutdownReq,
+		"textDocument/didOpen":              (*Server).DidOpen,
+		"textDocument/didChange":            (*Server).DidChange,
+		"textDocument/didClose":             (*Server).DidClose,
+		"textDocument/didSave":              (*Server).DidSave,
+		"textDocument/completion":           (*Server).Completion,
+		"textDocument/documentSymbol":       (*Server).DocumentSymbol,
+		"textDocument/definition":           (*Server).Definition,
+		"textDocument/hover":                (*Server).Hover,
+		"textDocument/prepareRename":        (*Server).PrepareRename,
+		"textDocument/rename":               (*Server).Rename,
+		"textDocument/signatureHelp":        (*Server).SignatureHelp,
+		"textDocument/foldingRange":         (*Server).FoldingRange,
+		"textDocument/semanticTokens/full":  (*Server).SemanticTokensFull,
+		"textDocument/semanticTokens/range": (*Server).SemanticTokensRange,
+		"workspace/symbol":                  (*Server).WorkspaceSymbol,
+	}
+}
+
+// Run reads and handles messages until exit is received or the input is closed,
+// returning the exit code
+func (sv *Server) Run() int {
+	for {
+		ms, err := ReadMessage(sv.In)
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return 1
+			}
+			var re *RespError
+			if errors.As(err, &re) {
+				sv.Reply(nil, nil, re)
+				continue
+			}
+			log.Println(err)
+			return 1
+		}
+		if ms.Method == "exit" {
+			return sv.ExitCode
+		}
+		sv.Handle(ms)
+	}
+}
+
+// Handle handles given message, sending the response for requests
+func (sv *Server) Handle(ms *Message) {
+	if ms.Method == "" { // response to a server request -- not used
+		return
+	}
+	if sv.Trace {
+		log.Printf("lsp: recv: %v %s\n", ms.Method, string(ms.Params))
+	}
+	notify := ms.IsNotify()
+	switch {
+	case !sv.Initialized && ms.Method != "initialize":
+		if !notify {
+			sv.Reply(ms.ID, nil, NewRespError(ServerNotInitialized, "server not initialized"))
+		}
+		return
+	case sv.Shutdown:
+		if !notify {
+			sv.Reply(ms.ID, nil, NewRespError(InvalidRequest, "server is shut down"))
+		}
+		return
+	}
+	hf, has := sv.Handlers[ms.Method]
+	if !has {
+		if !notify {
+			sv.Reply(ms.ID, nil, NewRespError(MethodNotFound, "method not supported: %v", ms.Method))
+		}
+		return
+	}
+	res, err := hf(sv, ms.Params)
+	if notify {
+		if err != nil {
+			log.Printf("lsp: %v: %v\n", ms.Method, err)
+		}
+		return
+	}
+	if err != nil {
+		var re *RespError
+		if !errors.As(err, &re) {
+			re = NewRespError(InternalError, "%v", err)
+		}
+		sv.Reply(ms.ID, nil, re)
+		return
+	}
+	sv.Reply(ms.ID, res, nil)
+}
+
+// Reply sends a response for given request id
+func (sv *Server) Reply(id *json.RawMessage, res interface{}, re *RespError) {
+	rs := &Response{JSONRPC: "2.0", ID: id, Result: res, Error: re}
+	sv.Send(rs)
+}
+
+// Notify sends a notification for given method to the client
+func (sv *Server) Notify(method string, params interface{}) {
+	sv.Send(&Notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// Send sends given message to the client, under the OutMu lock
+func (sv *Server) Send(v interface{}) {
+	sv.OutMu.Lock()
+	defer sv.OutMu.Unlock()
+	if sv.Trace {
+		b, _ := json.Marshal(v)
+		log.Printf("lsp: send: %s\n", string(b))
+	}
+	if err := WriteMessage(sv.Out, v); err != nil {
+		log.Println(err)
+	}
+}
+
+// DecodeParams decodes the params into given value, returning
+// an InvalidParams error if it fails
+func DecodeParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 {
+		return NewRespError(InvalidParams, "missing params")
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return NewRespError(InvalidParams, "%v", err)
+	}
+	return nil
+}
+
+// Doc returns the open document for given uri, or an error if not open
+func (sv *Server) Doc(uri DocumentURI) (*Document, error) {
+	sv.DocsMu.RLock()
+	defer sv.DocsMu.RUnlock()
+	dc, has := sv.Docs[uri]
+	if !has {
+		return nil, NewRespError(InvalidParams, "document not open: %v", uri)
+	}
+	return dc, nil
+}
+
+// OpenSrc returns the current source lines of the open document for given
+// absolute file path, and false if it is not open.  This is set as the
+// OpenSrc of the FileStates of each document, so that operations over other
+// files, such as rename, see their unsaved changes instead of the file on disk.
+func (sv *Server) OpenSrc(fname string) ([][]rune, bool) {
+	sv.DocsMu.RLock()
+	defer sv.DocsMu.RUnlock()
+	for _, dc := range sv.Docs {
+		if dc.Filename == fname {
+			return dc.Lines, true
+		}
+	}
+	return nil, false
+}
+
+/////////////////////////////////////////////////////////////////////////////
+//  Handlers
+
+// Nop is a handler that does nothing
+func (sv *Server) Nop(params json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+// Initialize handles the initialize request
+func (sv *Server) Initialize(params json.RawMessage) (interface{}, error) {
+	var ip InitializeParams
+	if err := DecodeParams(params, &ip); err != nil {
+		return nil, err
+	}
+	sv.RootPath = ip.RootPath
+	if ip.RootURI != "" {
+		sv.RootPath = URIToPath(ip.RootURI)
+	}
+	sv.Initialized = true
+	res := &InitializeResult{}
+	res.ServerInfo = ServerInfo{Name: "pi", Version: pi.Version}
+	sv.Capabilities(&res.Capabilities)
+	return res, nil
+}
+
+// Capabilities fills in the capabilities supported by the server
+func (sv *Server) Capabilities(cp *ServerCapabilities) {
+	cp.TextDocumentSync = TextDocumentSyncOptions{OpenClose: true, Change: SyncIncremental}
+	cp.CompletionProvider = &CompletionOptions{TriggerCharacters: CompletionTriggers}
+	cp.DocumentSymbolProvider = true
+	cp.WorkspaceSymbolProvider = true
+	cp.DefinitionProvider = true
+	cp.HoverProvider = true
+	cp.RenameProvider = &RenameOptions{PrepareProvider: true}
+	cp.SignatureHelpProvider = &SignatureHelpOptions{TriggerCharacters: SignatureTriggers}
+	cp.FoldingRangeProvider = true
+	cp.SemanticTokensProvider = &SemanticTokensOptions{Legend: SemanticTokensLegend{TokenTypes: SemanticTokenTypes, TokenModifiers: SemanticTokenModifiers}, Range: true, Full: true}
+}
+
+// ShutdownReq handles the shutdown request
+func (sv *Server) ShutdownReq(params json.RawMessage) (interface{}, error) {
+	sv.Shutdown = true
+	sv.ExitCode = 0
+	return nil, nil
+}
+
+// DidOpen handles the textDocument/didOpen notification
+func (sv *Server) DidOpen(params json.RawMessage) (interface{}, error) {
+	var dp DidOpenTextDocumentParams
+	if err := DecodeParams(params, &dp); err != nil {
+		return nil, err
+	}
+	dc := NewDocument(&dp.TextDocument, sv.RootPath)
+	dc.FileStates.OpenSrc = sv.OpenSrc
+	sv.DocsMu.Lock()
+	sv.Docs[dc.URI] = dc
+	sv.DocsMu.Unlock()
+	sv.DocUpdated(dc)
+	return nil, nil
+}
+
+// DidChange handles the textDocument/didChange notification -- a single
+// ranged change is re-parsed incrementally where the language supports it
+func (sv *Server) DidChange(params json.RawMessage) (interface{}, error) {
+	var dp DidChangeTextDocumentParams
+	if err := DecodeParams(params, &dp); err != nil {
+		return nil, err
+	}
+	dc, err := sv.Doc(dp.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	if len(dp.ContentChanges) == 1 && dp.ContentChanges[0].Range != nil && dc.Lang != nil {
+		st, nold, nnew := dc.ApplyChange(&dp.ContentChanges[0])
+		dc.Version = dp.TextDocument.Version
+		dc.Reparse(st, nold, nnew)
+		sv.PublishDiagnostics(dc)
+		return nil, nil
+	}
+	for i := range dp.ContentChanges {
+		dc.ApplyChange(&dp.ContentChanges[i])
+	}
+	dc.Version = dp.TextDocument.Version
+	sv.DocUpdated(dc)
+	return nil, nil
+}
+
+// DidSave handles the textDocument/didSave notification -- the workspace
+// symbols for the directory of the file are reloaded on next use
+func (sv *Server) DidSave(params json.RawMessage) (interface{}, error) {
+	var dp DidSaveTextDocumentParams
+	if err := DecodeParams(params, &dp); err != nil {
+		return nil, err
+	}
+	sv.ResetPkgSyms(filepath.Dir(URIToPath(dp.TextDocument.URI)))
+	return nil, nil
+}
+
+// DidClose handles the textDocument/didClose notification
+func (sv *Server) DidClose(params json.RawMessage) (interface{}, error) {
+	var dp DidCloseTextDocumentParams
+	if err := DecodeParams(params, &dp); err != nil {
+		return nil, err
+	}
+	sv.DocsMu.Lock()
+	defer sv.DocsMu.Unlock()
+	if _, has := sv.Docs[dp.TextDocument.URI]; !has {
+		return nil, fmt.Errorf("didClose: document not open: %v", dp.TextDocument.URI)
+	}
+	delete(sv.Docs, dp.TextDocument.URI)
+	sv.Notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{URI: dp.TextDocument.URI, Diagnostics: []Diagnostic{}})
+	return nil, nil
+}
+
+// DocUpdated is called whenever the document text has changed, and re-parses it,
+// pushing the resulting diagnostics to the client
+func (sv *Server) DocUpdated(dc *Document) {
+	dc.Parse()
+	sv.PublishDiagnostics(dc)
+}
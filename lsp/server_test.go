@@ -0,0 +1,393 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goki/pi/complete"
+	_ "github.com/goki/pi/langs/golang"
+	_ "github.com/goki/pi/langs/markdown"
+	"github.com/goki/pi/lex"
+	"github.com/goki/pi/pi"
+	"github.com/goki/pi/token"
+)
+
+func init() {
+	pi.LangSupport.OpenStd()
+}
+
+// testMsg returns a framed client message
+func testMsg(id int, method string, params interface{}) []byte {
+	ms := map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+	if id > 0 {
+		ms["id"] = id
+	}
+	var b bytes.Buffer
+	WriteMessage(&b, ms)
+	return b.Bytes()
+}
+
+// testReplies reads all the replies written by the server
+func testReplies(t *testing.T, out *bytes.Buffer) []*Message {
+	var rs []*Message
+	rd := bufio.NewReader(out)
+	for {
+		ms, err := ReadMessage(rd)
+		if err != nil {
+			break
+		}
+		rs = append(rs, ms)
+	}
+	return rs
+}
+
+const testGoSrc = `package main
+
+type Foo struct {
+	Bar int
+}
+
+func (f *Foo) Get() int {
+	return f.Bar
+}
+
+func main() {
+	f := &Foo{}
+	f.Get()
+}
+`
+
+func TestServerLifecycle(t *testing.T) {
+	uri := PathToURI("/tmp/pilsptest/main.go")
+	var in bytes.Buffer
+	in.Write(testMsg(1, "textDocument/hover", nil)) // before initialize
+	in.Write(testMsg(2, "initialize", map[string]interface{}{"rootUri": PathToURI("/tmp/pilsptest")}))
+	in.Write(testMsg(0, "initialized", struct{}{}))
+	in.Write(testMsg(0, "textDocument/didOpen", map[string]interface{}{
+		"textDocument": TextDocumentItem{URI: uri, LanguageID: "go", Version: 1, Text: testGoSrc},
+	}))
+	in.Write(testMsg(0, "textDocument/didChange", map[string]interface{}{
+		"textDocument":   VersionedTextDocumentIdentifier{URI: uri, Version: 2},
+		"contentChanges": []TextDocumentContentChangeEvent{{Range: &Range{Start: Position{Line: 3, Character: 1}, End: Position{Line: 3, Character: 4}}, Text: "Baz"}},
+	}))
+	in.Write(testMsg(3, "noSuchMethod", struct{}{}))
+	in.Write(testMsg(4, "shutdown", nil))
+	in.Write(testMsg(0, "exit", nil))
+
+	var out bytes.Buffer
+	sv := NewServer(&in, &out)
+	code := sv.Run()
+	if code != 0 {
+		t.Errorf("exit code: %v != 0", code)
+	}
+	dc, has := sv.Docs[uri]
+	if !has {
+		t.Fatalf("document not open: %v", uri)
+	}
+	if dc.Lang == nil {
+		t.Errorf("document has no Lang, sup: %v", dc.Sup)
+	}
+	if dc.Version != 2 {
+		t.Errorf("version: %v != 2", dc.Version)
+	}
+	if got := string(dc.Lines[3]); got != "\tBaz int" {
+		t.Errorf("didChange: line 3 is %q", got)
+	}
+	fs := dc.FileStates.Done()
+	if fs.Ast.NumChildren() == 0 {
+		t.Errorf("document was not parsed")
+	}
+
+	var rs, ns []*Message
+	for _, ms := range testReplies(t, &out) {
+		if ms.Method != "" {
+			ns = append(ns, ms)
+		} else {
+			rs = append(rs, ms)
+		}
+	}
+	if len(ns) != 2 || ns[0].Method != "textDocument/publishDiagnostics" {
+		t.Errorf("expected 2 diagnostics notifications, got: %v", len(ns))
+	}
+	if len(rs) != 4 {
+		t.Fatalf("expected 4 replies, got: %v", len(rs))
+	}
+	codes := []int{ServerNotInitialized, 0, MethodNotFound, 0}
+	for i, rs := range rs {
+		code := 0
+		if rs.Error != nil {
+			code = rs.Error.Code
+		}
+		if code != codes[i] {
+			t.Errorf("reply %d: error code %v != %v", i, code, codes[i])
+		}
+	}
+	var ir InitializeResult
+	json.Unmarshal(rs[1].Result, &ir)
+	if ir.ServerInfo.Name != "pi" || ir.Capabilities.TextDocumentSync.Change != SyncIncremental {
+		t.Errorf("bad initialize result: %v", string(rs[1].Result))
+	}
+}
+
+func TestPosUTF16(t *testing.T) {
+	dc := &Document{}
+	dc.SetText("a𝄞b\nx")
+	ps := dc.PosToLSP(dc.PosFromLSP(Position{Line: 0, Character: 3}))
+	if ps.Character != 3 {
+		t.Errorf("round trip: %v", ps)
+	}
+	lp := dc.PosFromLSP(Position{Line: 0, Character: 3})
+	if lp.Ch != 2 {
+		t.Errorf("rune index: %v != 2", lp.Ch)
+	}
+}
+
+func TestCompletionItem(t *testing.T) {
+	dc := &Document{}
+	dc.SetText("\tx := ChildBy()")
+	pos := lex.Pos{Ln: 0, Ch: 11} // just after "Child"
+	c := complete.Completion{Text: "ChildByName()", Icon: "method"}
+	ed := complete.Edit{NewText: "ChildByName", ForwardDelete: 2}
+	ci := dc.CompletionItem(pos, &c, &ed, "Child", 0)
+	if ci.Kind != int(CkMethod) {
+		t.Errorf("kind: %v != %v", ci.Kind, CkMethod)
+	}
+	want := Range{Start: Position{Line: 0, Character: 6}, End: Position{Line: 0, Character: 13}}
+	if ci.TextEdit.Range != want || ci.TextEdit.NewText != "ChildByName" {
+		t.Errorf("edit: %+v", ci.TextEdit)
+	}
+	ed.CursorAdjust = -1
+	ed.NewText = "Child()"
+	ci = dc.CompletionItem(pos, &c, &ed, "Child", 0)
+	if ci.InsertTextFormat != SnippetFormat || ci.TextEdit.NewText != "Child($0)" {
+		t.Errorf("cursor adjust: %+v", ci.TextEdit)
+	}
+}
+
+func TestDocumentSymbol(t *testing.T) {
+	sv := NewServer(&bytes.Buffer{}, &bytes.Buffer{})
+	uri := PathToURI("/tmp/pilsptest/syms.go")
+	sv.Docs[uri] = NewDocument(&TextDocumentItem{URI: uri, Text: testGoSrc}, "")
+	sv.DocUpdated(sv.Docs[uri])
+	params, _ := json.Marshal(DocumentSymbolParams{TextDocument: TextDocumentIdentifier{URI: uri}})
+	res, err := sv.DocumentSymbol(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds := res.([]DocumentSymbol)
+	if len(ds) != 2 || ds[0].Name != "Foo" || ds[1].Name != "main" {
+		t.Fatalf("top-level symbols: %+v", ds)
+	}
+	if len(ds[0].Children) != 2 || ds[0].Children[1].Name != "Get" || ds[0].Children[1].Kind != int(Method) {
+		t.Errorf("Foo children: %+v", ds[0].Children)
+	}
+	want := Range{Start: Position{Line: 10, Character: 5}, End: Position{Line: 10, Character: 9}}
+	if ds[1].SelectionRange != want {
+		t.Errorf("main selection range: %+v", ds[1].SelectionRange)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	uri := PathToURI("/tmp/pilsptest/diag.go")
+	src := "package main\n\nfunc main() {\n\tx := (1 + 2\n}\n"
+	dc := NewDocument(&TextDocumentItem{URI: uri, Text: src}, "")
+	dc.Parse()
+	ds := dc.Diagnostics()
+	if len(ds) == 0 {
+		t.Fatalf("no diagnostics for unbalanced paren")
+	}
+	gotNest := false
+	for _, d := range ds {
+		if d.Source == "nesting" {
+			gotNest = true
+			if strings.HasPrefix(d.Message, "PassTwo: ") {
+				t.Errorf("prefix not removed: %v", d.Message)
+			}
+		}
+	}
+	if !gotNest {
+		t.Errorf("no nesting diagnostic: %+v", ds)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	sv := NewServer(&bytes.Buffer{}, &bytes.Buffer{})
+	uri := PathToURI("/tmp/pilsptest/lookup.go")
+	sv.Docs[uri] = NewDocument(&TextDocumentItem{URI: uri, Text: testGoSrc}, "")
+	sv.DocUpdated(sv.Docs[uri])
+	params, _ := json.Marshal(TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: Position{Line: 11, Character: 8}})
+	res, err := sv.Definition(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc, ok := res.(*Location)
+	want := Range{Start: Position{Line: 2, Character: 5}, End: Position{Line: 2, Character: 8}}
+	if !ok || loc.URI != uri || loc.Range != want {
+		t.Fatalf("definition of Foo: %+v", res)
+	}
+	res, err = sv.Hover(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hv, ok := res.(*Hover)
+	if !ok || !strings.Contains(hv.Contents.Value, "type Foo struct {") {
+		t.Errorf("hover of Foo: %+v", res)
+	}
+}
+
+func TestRename(t *testing.T) {
+	sv := NewServer(&bytes.Buffer{}, &bytes.Buffer{})
+	fname, _ := filepath.Abs("../langs/golang/testdata/refs/main.go")
+	src, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri := PathToURI(fname)
+	sv.Docs[uri] = NewDocument(&TextDocumentItem{URI: uri, Text: string(src)}, "")
+	sv.DocUpdated(sv.Docs[uri])
+	tp := TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: Position{Line: 2, Character: 6}}
+	params, _ := json.Marshal(tp)
+	res, err := sv.PrepareRename(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr := res.(*PrepareRenameResult); pr.Placeholder != "Foo" {
+		t.Errorf("prepare rename: %+v", pr)
+	}
+	params, _ = json.Marshal(RenameParams{TextDocumentPositionParams: tp, NewName: "Baz"})
+	res, err = sv.Rename(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	we := res.(*WorkspaceEdit)
+	if len(we.Changes) != 2 || len(we.Changes[uri]) != 3 {
+		t.Errorf("rename edits: %+v", we.Changes)
+	}
+	params, _ = json.Marshal(RenameParams{TextDocumentPositionParams: tp, NewName: "Helper"})
+	if _, err = sv.Rename(params); err == nil {
+		t.Errorf("rename to Helper: expected clash error")
+	}
+
+	// unsaved changes in an open other.go are used instead of the file on disk
+	sv.Docs[uri].FileStates.OpenSrc = sv.OpenSrc
+	ouri := PathToURI(filepath.Join(filepath.Dir(fname), "other.go"))
+	sv.Docs[ouri] = NewDocument(&TextDocumentItem{URI: ouri, Text: "package main\n\nvar g, h Foo\n\nfunc Helper(y int) int {\n\tvar f Foo\n\treturn f.Get() + y\n}\n"}, "")
+	params, _ = json.Marshal(RenameParams{TextDocumentPositionParams: tp, NewName: "Baz"})
+	res, err = sv.Rename(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oeds := res.(*WorkspaceEdit).Changes[ouri]
+	if len(oeds) != 2 || oeds[0].Range.Start != (Position{Line: 2, Character: 9}) || oeds[1].Range.Start != (Position{Line: 5, Character: 7}) {
+		t.Errorf("rename edits in open other.go: %+v", oeds)
+	}
+}
+
+func TestFoldingRange(t *testing.T) {
+	sv := NewServer(&bytes.Buffer{}, &bytes.Buffer{})
+	uri := PathToURI("/tmp/pilsptest/fold.md")
+	src := "# Top\n\ntext\n\n## Sub\n\n```go\nx := 1\n```\n\n# Next\nmore\n"
+	sv.Docs[uri] = NewDocument(&TextDocumentItem{URI: uri, Text: src}, "")
+	sv.DocUpdated(sv.Docs[uri])
+	params, _ := json.Marshal(FoldingRangeParams{TextDocument: TextDocumentIdentifier{URI: uri}})
+	res, err := sv.FoldingRange(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frs := res.([]FoldingRange)
+	want := [][2]int{{0, 8}, {4, 8}, {6, 8}, {10, 11}}
+	if len(frs) != len(want) {
+		t.Fatalf("folding ranges: %+v", frs)
+	}
+	for i, w := range want {
+		if frs[i].StartLine != w[0] || frs[i].EndLine != w[1] || frs[i].Kind != "region" {
+			t.Errorf("folding range %d: got %+v, want lines %v", i, frs[i], w)
+		}
+	}
+}
+
+func TestSemanticTokenTypes(t *testing.T) {
+	for tk := token.None; tk < token.TokensN; tk++ {
+		if _, ok := CatSemanticTypeMap[tk.Cat()]; !ok {
+			t.Errorf("token: %v category: %v not in CatSemanticTypeMap", tk, tk.Cat())
+		}
+		if st := SemanticTokenTypeName(tk); st != "" && SemanticTokenType(tk) < 0 {
+			t.Errorf("token: %v type: %q not in SemanticTokenTypes", tk, st)
+		}
+	}
+	want := map[token.Tokens]string{
+		token.IndentBlock:    "", // zero-width, from Text
+		token.NameOther:      "variable",
+		token.NameConstant:   "variable",
+		token.NameStruct:     "struct",
+		token.NameNamespace:  "namespace",
+		token.NameTag:        "property",
+		token.LitStrBacktick: "string",
+		token.LitNumHex:      "number",
+		token.OpMathAdd:      "operator",
+		token.CommentPreproc: "comment",
+		token.PunctGpLParen:  "",
+	}
+	for tk, st := range want {
+		if got := SemanticTokenTypeName(tk); got != st {
+			t.Errorf("semantic type for: %v got %q, want %q", tk, got, st)
+		}
+	}
+}
+
+func TestSemanticTokens(t *testing.T) {
+	sv := NewServer(&bytes.Buffer{}, &bytes.Buffer{})
+	uri := PathToURI("/tmp/pilsptest/semtok.go")
+	sv.Docs[uri] = NewDocument(&TextDocumentItem{URI: uri, Text: testGoSrc}, "")
+	sv.DocUpdated(sv.Docs[uri])
+	params, _ := json.Marshal(SemanticTokensParams{TextDocument: TextDocumentIdentifier{URI: uri}})
+	res, err := sv.SemanticTokensFull(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := res.(*SemanticTokens).Data
+	if len(data)%5 != 0 {
+		t.Fatalf("semantic tokens data not in groups of 5: %v", data)
+	}
+	toks := make(map[Position]string) // decoded: start -> type
+	var ln, ch uint32
+	for i := 0; i < len(data); i += 5 {
+		if data[i] > 0 {
+			ch = 0
+		}
+		ln += data[i]
+		ch += data[i+1]
+		toks[Position{Line: int(ln), Character: int(ch)}] = SemanticTokenTypes[data[i+3]]
+	}
+	want := map[Position]string{
+		{Line: 0, Character: 0}:  "keyword",  // package
+		{Line: 2, Character: 5}:  "struct",   // Foo
+		{Line: 6, Character: 14}: "method",   // Get
+		{Line: 10, Character: 5}: "function", // main
+		{Line: 7, Character: 1}:  "keyword",  // return
+	}
+	for ps, ty := range want {
+		if toks[ps] != ty {
+			t.Errorf("semantic token at %v: got %q, want %q -- all: %v", ps, toks[ps], ty, toks)
+		}
+	}
+
+	params, _ = json.Marshal(SemanticTokensRangeParams{TextDocument: TextDocumentIdentifier{URI: uri}, Range: Range{Start: Position{Line: 2}, End: Position{Line: 3}}})
+	res, err = sv.SemanticTokensRange(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = res.(*SemanticTokens).Data
+	if len(data) != 15 || data[0] != 2 || data[1] != 0 || data[5] != 0 || data[6] != 5 || data[7] != 3 {
+		t.Errorf("semantic tokens for line 2: %v", data)
+	}
+}
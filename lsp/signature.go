@@ -0,0 +1,57 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/goki/pi/pi"
+)
+
+// SignatureTriggers are the characters that trigger signature help in the client:
+// ( to start a call and , to move to the next argument
+var SignatureTriggers = []string{"(", ","}
+
+// SignatureLines is the number of lines before the cursor line that are
+// passed to SignatureHelp, so that calls spanning multiple lines are found
+var SignatureLines = 5
+
+// SignatureHelp handles the textDocument/signatureHelp request, using
+// the pi.SignatureHelper interface of the document language, if supported,
+// for the call enclosing the cursor.  Returns null if not in a known call.
+func (sv *Server) SignatureHelp(params json.RawMessage) (interface{}, error) {
+	var tp TextDocumentPositionParams
+	if err := DecodeParams(params, &tp); err != nil {
+		return nil, err
+	}
+	dc, err := sv.Doc(tp.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	sh, ok := dc.Lang.(pi.SignatureHelper)
+	if !ok {
+		return nil, nil
+	}
+	pos := dc.PosFromLSP(tp.Position)
+	st := pos.Ln - SignatureLines
+	if st < 0 {
+		st = 0
+	}
+	var sb strings.Builder
+	for ln := st; ln < pos.Ln; ln++ {
+		sb.WriteString(string(dc.Lines[ln]) + "\n")
+	}
+	sb.WriteString(string(dc.Lines[pos.Ln][:pos.Ch]))
+	sg, ok := sh.SignatureHelp(dc.FileStates, sb.String(), pos)
+	if !ok {
+		return nil, nil
+	}
+	si := SignatureInformation{Label: sg.Label(), Documentation: sg.Desc, Parameters: []ParameterInformation{}}
+	for _, pr := range sg.Params {
+		si.Parameters = append(si.Parameters, ParameterInformation{Label: pr})
+	}
+	return &SignatureHelp{Signatures: []SignatureInformation{si}, ActiveParameter: sg.ActiveParam}, nil
+}
@@ -94,7 +94,24 @@ var SymbolKindTokenMap = map[SymbolKind]token.Tokens{
 var TokenSymbolKindMap map[token.Tokens]SymbolKind
 
 func init() {
+	TokenSymbolKindMap = make(map[token.Tokens]SymbolKind, len(SymbolKindTokenMap))
 	for s, t := range SymbolKindTokenMap {
 		TokenSymbolKindMap[t] = s
 	}
+	TokenSymbolKindMap[token.NameType] = Class
+}
+
+// SymbolKindForToken returns the SymbolKind for given token,
+// trying the sub-category and category of the token if not found directly
+func SymbolKindForToken(tk token.Tokens) SymbolKind {
+	if sk, ok := TokenSymbolKindMap[tk]; ok {
+		return sk
+	}
+	if sk, ok := TokenSymbolKindMap[tk.SubCat()]; ok {
+		return sk
+	}
+	if sk, ok := TokenSymbolKindMap[tk.Cat()]; ok {
+		return sk
+	}
+	return Variable
 }
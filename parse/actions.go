@@ -6,6 +6,9 @@ package parse
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/goki/ki/kit"
 	"github.com/goki/pi/lex"
@@ -125,6 +128,40 @@ func (ac Acts) String() string {
 	return str
 }
 
+// actsRe matches one Act as written by Act.String, followed by ;
+var actsRe = regexp.MustCompile(`(-?\d+):(\w+):"(.*?)":(\w+)(?:<-(\w+))?;`)
+
+// FromString sets the actions from the format written by String,
+// e.g., { -1:ChgToken:"Name":NameFunction; }
+func (ac *Acts) FromString(str string) error {
+	*ac = nil
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return nil
+	}
+	if !strings.HasPrefix(str, "{") || !strings.HasSuffix(str, "}") {
+		return fmt.Errorf("parse.Acts FromString: not enclosed in { }: %v", str)
+	}
+	for _, m := range actsRe.FindAllStringSubmatch(str, -1) {
+		var a Act
+		a.RunIdx, _ = strconv.Atoi(m[1])
+		if err := a.Act.FromString(m[2]); err != nil {
+			return err
+		}
+		a.Path = m[3]
+		if err := a.Tok.FromString(m[4]); err != nil {
+			return err
+		}
+		if m[5] != "" {
+			if err := a.FmTok.FromString(m[5]); err != nil {
+				return err
+			}
+		}
+		*ac = append(*ac, a)
+	}
+	return nil
+}
+
 // AstActs are actions to perform on the Ast nodes
 type AstActs int
 
@@ -0,0 +1,586 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parse
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/goki/ki/ki"
+	"github.com/goki/ki/kit"
+	"github.com/goki/pi/token"
+)
+
+// GrammarIssues are the kinds of issues found in the grammar by Rule.Analyze
+type GrammarIssues int
+
+//go:generate stringer -type=GrammarIssues
+
+var KiT_GrammarIssues = kit.Enums.AddEnum(GrammarIssuesN, kit.NotBitFlag, nil)
+
+func (ev GrammarIssues) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(ev) }
+func (ev *GrammarIssues) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(ev, b) }
+
+// The grammar issues
+const (
+	// UnreachableRule is a rule that is never used in parsing: it is not the
+	// start rule (the first child of the top-level rule), nor referenced
+	// from, or a child of, any rule that is reachable from it.
+	UnreachableRule GrammarIssues = iota
+
+	// ShadowedRule is a rule in a group that never matches, because an
+	// earlier sibling has the same elements used for matching, or is a
+	// single token that covers all of its possible first tokens, or has
+	// the same first token in a FirstTokMap, and thus always matches first
+	// -- it only runs if the earlier one fails after matching.
+	ShadowedRule
+
+	// LeftRecursion is a cycle of rules that match each other within the
+	// same scope without any token to anchor the match, resulting in
+	// infinite recursion up to the DepthLimit.
+	LeftRecursion
+
+	// BadActPath is an Act whose Path can never resolve to a node in the
+	// Ast of the rule, or an Act on a group rule, which is never run.
+	BadActPath
+
+	GrammarIssuesN
+)
+
+// GrammarIssue is an issue found in the grammar by Rule.Analyze
+type GrammarIssue struct {
+
+	// the kind of issue
+	Kind GrammarIssues `desc:"the kind of issue"`
+
+	// the rule with the issue
+	Rule *Rule `desc:"the rule with the issue"`
+
+	// description of the issue
+	Msg string `desc:"description of the issue"`
+}
+
+// String satisfies fmt.Stringer interface
+func (gi GrammarIssue) String() string {
+	return fmt.Sprintf("%v: %v: %v", gi.Kind, gi.Rule.Name(), gi.Msg)
+}
+
+// Analyze is called on the top-level Rule, after CompileAll and Validate,
+// to do a static analysis of the grammar for rules that can never run or
+// are likely errors, beyond the basic structure checked by Validate:
+// unreachable rules, rules shadowed by an earlier sibling, left-recursive
+// cycles without token anchors, and Acts whose Path can never resolve.
+// Returns the issues in the order of the rules.
+func (pr *Rule) Analyze(ps *State) []GrammarIssue {
+	ga := &grammarAnalyzer{root: pr, names: make(map[string]*Rule)}
+	pr.FuncDownMeFirst(0, pr.This(), func(k ki.Ki, level int, d any) bool {
+		pri := k.(*Rule)
+		if pri.Off {
+			return ki.Break
+		}
+		ga.rules = append(ga.rules, pri)
+		if _, has := ga.names[pri.Nm]; !has {
+			ga.names[pri.Nm] = pri
+		}
+		return ki.Continue
+	})
+	ga.unreachable()
+	ga.shadowed()
+	ga.leftRecursion()
+	ga.actPaths()
+	order := make(map[*Rule]int, len(ga.rules))
+	for i, r := range ga.rules {
+		order[r] = i
+	}
+	sort.SliceStable(ga.issues, func(i, j int) bool {
+		return order[ga.issues[i].Rule] < order[ga.issues[j].Rule]
+	})
+	return ga.issues
+}
+
+// grammarAnalyzer has the state for Rule.Analyze
+type grammarAnalyzer struct {
+	root   *Rule
+	rules  []*Rule                   // all rules that are not Off, in tree order
+	names  map[string]*Rule          // rules by name
+	issues []GrammarIssue            // issues found
+	prod   map[*Rule]map[string]bool // see produced
+	kids   map[*Rule]map[string]bool // see produced
+	reach  map[*Rule]bool            // rules reachable from the start rule
+}
+
+func (ga *grammarAnalyzer) add(kind GrammarIssues, pr *Rule, msg string) {
+	ga.issues = append(ga.issues, GrammarIssue{Kind: kind, Rule: pr, Msg: msg})
+}
+
+// subRules calls fun for each sub-rule in the Rules of given rule,
+// with its index
+func subRules(pr *Rule, fun func(ri int, sr *Rule)) {
+	for ri := range pr.Rules {
+		if sr := pr.Rules[ri].Rule; sr != nil && !sr.Off {
+			fun(ri, sr)
+		}
+	}
+}
+
+// unreachable reports the rules that are not reachable from the start rule,
+// other than those that just contain reachable rules -- only the top-most
+// rule of an unreachable branch is reported
+func (ga *grammarAnalyzer) unreachable() {
+	ga.reach = make(map[*Rule]bool)
+	if !ga.root.HasChildren() {
+		return
+	}
+	var visit func(pr *Rule)
+	visit = func(pr *Rule) {
+		if pr.Off || ga.reach[pr] {
+			return
+		}
+		ga.reach[pr] = true
+		subRules(pr, func(ri int, sr *Rule) { visit(sr) })
+		for _, k := range pr.Kids {
+			visit(k.(*Rule))
+		}
+	}
+	visit(ga.root.Kids[0].(*Rule))
+	for _, pr := range ga.rules {
+		if pr == ga.root || ga.reach[pr] {
+			continue
+		}
+		if par, ok := pr.Par.(*Rule); ok && par != ga.root && !ga.reach[par] && !ga.container(par) {
+			continue // reported for parent
+		}
+		if ga.container(pr) {
+			continue
+		}
+		ga.add(UnreachableRule, pr, "rule is not reachable from start rule: "+ga.root.Kids[0].Name())
+	}
+}
+
+// container returns true if given unreachable rule contains reachable rules
+func (ga *grammarAnalyzer) container(pr *Rule) bool {
+	has := false
+	pr.FuncDownMeFirst(0, nil, func(k ki.Ki, level int, d any) bool {
+		if has || ga.reach[k.(*Rule)] {
+			has = true
+			return ki.Break
+		}
+		return ki.Continue
+	})
+	return has
+}
+
+// matchSig returns a signature of the elements used for matching the rule,
+// such that two rules with the same signature match the same input
+func (pr *Rule) matchSig() string {
+	var sb strings.Builder
+	nr := len(pr.Rules)
+	lr := pr.Rules.Last()
+	fmt.Fprintf(&sb, "%d %v %v %v %v %v %v %d %v|", nr, pr.HasFlag(int(Reverse)), pr.HasFlag(int(NoToks)), pr.HasFlag(int(OnlyToks)), pr.HasFlag(int(SetsScope)), pr.HasFlag(int(MatchEOS)), pr.HasFlag(int(MultiEOS)), lr.StInc, lr.Opt)
+	for _, ri := range pr.Order {
+		rr := &pr.Rules[ri]
+		el := ""
+		if rr.IsRule() {
+			el = "@" + rr.Rule.Name()
+		} else {
+			el = fmt.Sprintf("%v+%d", rr.Tok.StringKey(), rr.Tok.Depth)
+		}
+		fmt.Fprintf(&sb, "%d:%s:%d:%v;", ri, el, rr.StInc, rr.FmNext)
+	}
+	return sb.String()
+}
+
+// shadowed reports rules in reachable groups that never match because an
+// earlier sibling always matches first: one with the same match signature,
+// that is not conditional on the StackMatch or exclusion rules, or one
+// that matches on just a single first token, which covers the first-token
+// set of the later one.  In a FirstTokMap group, a rule in the part looked
+// up by first token is also shadowed by an earlier one with the same first
+// token, as it is never looked up.  Groups that are not reachable just
+// contain rules referenced by name, which are not alternatives.
+func (ga *grammarAnalyzer) shadowed() {
+	for _, pr := range ga.rules {
+		if !pr.HasChildren() || !ga.reach[pr] {
+			continue
+		}
+		sigs := make(map[string]*Rule)
+		var firsts []*Rule             // earlier siblings that match on a single first token
+		fmap := make(map[string]*Rule) // for FirstTokMap: rules by first token in the looked up part
+		inMap := pr.FirstTokMap
+		for _, k := range pr.Kids {
+			kpr := k.(*Rule)
+			if kpr.Off {
+				continue
+			}
+			if inMap && (len(kpr.Rules) == 0 || !kpr.Rules[0].IsToken()) {
+				inMap = false
+			}
+			if inMap {
+				skey := kpr.Rules[0].Tok.StringKey()
+				if epr, has := fmap[skey]; has {
+					ga.add(ShadowedRule, kpr, fmt.Sprintf("never matches: earlier sibling: %v has the same first token: %v in the FirstTokMap and is always looked up instead", epr.Name(), kpr.Rules[0].Tok))
+					continue
+				}
+				fmap[skey] = kpr
+			}
+			if ktoks, ok := ga.firstToks(kpr, make(map[*Rule]bool)); ok && len(ktoks) > 0 {
+				if epr := coveringFirst(firsts, ktoks, pr.FirstTokMap); epr != nil {
+					ga.add(ShadowedRule, kpr, fmt.Sprintf("never matches: earlier sibling: %v matches just on its first token: %v, which covers all the first tokens of this rule, and always matches first", epr.Name(), epr.Rules[0].Tok))
+					continue
+				}
+			}
+			if kpr.HasChildren() || len(kpr.Rules) == 0 {
+				continue
+			}
+			sig := kpr.matchSig()
+			if epr, has := sigs[sig]; has {
+				ga.add(ShadowedRule, kpr, fmt.Sprintf("never matches: earlier sibling: %v has the same matching elements and always matches first", epr.Name()))
+				continue
+			}
+			if kpr.StackMatch == "" && len(kpr.ExclFwd) == 0 && len(kpr.ExclRev) == 0 {
+				sigs[sig] = kpr
+				if kpr.singleFirst() {
+					firsts = append(firsts, kpr)
+				}
+			}
+		}
+	}
+}
+
+// singleFirst returns true if the rule is matched just on the token at
+// the start of its scope, which it thus always matches
+func (pr *Rule) singleFirst() bool {
+	if len(pr.Rules) != 1 || len(pr.Order) != 1 || pr.HasChildren() {
+		return false
+	}
+	rr := &pr.Rules[0]
+	if !rr.IsToken() || rr.Opt || rr.FmNext || rr.Tok.Depth != 0 || rr.Tok.Tok == token.None {
+		return false
+	}
+	return !pr.HasFlag(int(Reverse)) && !pr.HasFlag(int(MatchEOS)) && !pr.HasFlag(int(MultiEOS))
+}
+
+// firstToks returns the tokens that can start the scope of a match of
+// given rule: the first token of the rule if it is used for matching, or
+// the first tokens of its first sub-rule if that is matched, or of all the
+// children of a group.  Returns false if they cannot be determined.
+func (ga *grammarAnalyzer) firstToks(pr *Rule, visiting map[*Rule]bool) ([]token.KeyToken, bool) {
+	if pr.Off || visiting[pr] || pr.StackMatch != "" {
+		return nil, false
+	}
+	visiting[pr] = true
+	defer delete(visiting, pr)
+	if len(pr.Rules) == 0 {
+		var toks []token.KeyToken
+		for _, k := range pr.Kids {
+			kpr := k.(*Rule)
+			if kpr.Off {
+				continue
+			}
+			ktoks, ok := ga.firstToks(kpr, visiting)
+			if !ok {
+				return nil, false
+			}
+			toks = append(toks, ktoks...)
+		}
+		return toks, len(toks) > 0
+	}
+	rr := &pr.Rules[0]
+	if rr.Opt || rr.FmNext || pr.HasFlag(int(Reverse)) || !inOrder(pr.Order, 0) {
+		return nil, false
+	}
+	if rr.IsRule() {
+		return ga.firstToks(rr.Rule, visiting)
+	}
+	if rr.Tok.Depth != 0 || rr.Tok.Tok == token.None {
+		return nil, false
+	}
+	return []token.KeyToken{rr.Tok}, true
+}
+
+// inOrder returns true if the rule element index is in the match order
+func inOrder(order []int, ri int) bool {
+	for _, oi := range order {
+		if oi == ri {
+			return true
+		}
+	}
+	return false
+}
+
+// coveringFirst returns the first of given single first token rules whose
+// token matches all of the given tokens, or nil if none -- if exact, the
+// tokens must be the same, as for the lookup in a FirstTokMap
+func coveringFirst(firsts []*Rule, toks []token.KeyToken, exact bool) *Rule {
+	for _, epr := range firsts {
+		et := epr.Rules[0].Tok
+		all := true
+		for _, kt := range toks {
+			if (exact && et.StringKey() != kt.StringKey()) || (!exact && !et.Match(kt)) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return epr
+		}
+	}
+	return nil
+}
+
+// matchEdges returns the rules that given rule matches within its own scope
+// before matching any token: all the children of a group, the first rule of a
+// rule without tokens, and the first rule to be matched in the Order of others
+func (ga *grammarAnalyzer) matchEdges(pr *Rule) []*Rule {
+	var eds []*Rule
+	if pr.HasFlag(int(TokMatchGroup)) || len(pr.Rules) == 0 {
+		for _, k := range pr.Kids {
+			if kpr := k.(*Rule); !kpr.Off {
+				eds = append(eds, kpr)
+			}
+		}
+		return eds
+	}
+	if len(pr.Order) == 0 {
+		return nil
+	}
+	if rr := &pr.Rules[pr.Order[0]]; rr.IsRule() && !rr.Rule.Off {
+		eds = append(eds, rr.Rule)
+	}
+	return eds
+}
+
+// leftRecursion reports cycles in the matchEdges graph, as the strongly
+// connected components of the graph (Tarjan's algorithm), once per cycle
+func (ga *grammarAnalyzer) leftRecursion() {
+	idx := make(map[*Rule]int)
+	low := make(map[*Rule]int)
+	onStack := make(map[*Rule]bool)
+	var stack []*Rule
+	n := 0
+	var connect func(pr *Rule)
+	connect = func(pr *Rule) {
+		idx[pr] = n
+		low[pr] = n
+		n++
+		stack = append(stack, pr)
+		onStack[pr] = true
+		for _, e := range ga.matchEdges(pr) {
+			if _, has := idx[e]; !has {
+				connect(e)
+				if low[e] < low[pr] {
+					low[pr] = low[e]
+				}
+			} else if onStack[e] && idx[e] < low[pr] {
+				low[pr] = idx[e]
+			}
+		}
+		if low[pr] != idx[pr] {
+			return
+		}
+		var comp []*Rule
+		for {
+			e := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[e] = false
+			comp = append(comp, e)
+			if e == pr {
+				break
+			}
+		}
+		ga.cycle(comp)
+	}
+	for _, pr := range ga.rules {
+		if _, has := idx[pr]; !has {
+			connect(pr)
+		}
+	}
+}
+
+// cycle reports a left-recursive cycle for given strongly connected
+// component, if it is a cycle
+func (ga *grammarAnalyzer) cycle(comp []*Rule) {
+	in := make(map[*Rule]bool, len(comp))
+	for _, pr := range comp {
+		in[pr] = true
+	}
+	st := comp[len(comp)-1] // first visited: where the cycle is entered
+	if len(comp) == 1 {
+		self := false
+		for _, e := range ga.matchEdges(st) {
+			if e == st {
+				self = true
+			}
+		}
+		if !self {
+			return
+		}
+	}
+	// path from st back to itself within the component
+	path := []string{st.Name()}
+	seen := map[*Rule]bool{st: true}
+	for cur := st; ; {
+		var nxt *Rule
+		for _, e := range ga.matchEdges(cur) {
+			if e == st {
+				nxt = e
+				break
+			}
+			if in[e] && !seen[e] && nxt == nil {
+				nxt = e
+			}
+		}
+		if nxt == nil {
+			break
+		}
+		path = append(path, nxt.Name())
+		if nxt == st {
+			break
+		}
+		seen[nxt] = true
+		cur = nxt
+	}
+	ga.add(LeftRecursion, st, "left-recursive cycle without token anchors: "+strings.Join(path, " -> "))
+}
+
+// produced computes the names of the Ast nodes that each rule can add to
+// the Ast node that it is parsed into (prod), and the names of the
+// children of the Ast node that each rule creates (kids), iterating to a fixed
+// point over the recursion in the grammar.  The sets include all possible
+// names, erring on the side of more names, so that paths missing from them
+// can never resolve.
+func (ga *grammarAnalyzer) produced() {
+	ga.prod = make(map[*Rule]map[string]bool)
+	ga.kids = make(map[*Rule]map[string]bool)
+	for _, pr := range ga.rules {
+		ga.prod[pr] = make(map[string]bool)
+		ga.kids[pr] = make(map[string]bool)
+	}
+	addAll := func(to, fm map[string]bool) bool {
+		chg := false
+		for nm := range fm {
+			if !to[nm] {
+				to[nm] = true
+				chg = true
+			}
+		}
+		return chg
+	}
+	for chg := true; chg; {
+		chg = false
+		for _, pr := range ga.rules {
+			prod := ga.prod[pr]
+			kids := ga.kids[pr]
+			if pr.Ast != NoAst && !prod[pr.Nm] {
+				prod[pr.Nm] = true
+				chg = true
+			}
+			if pr.HasFlag(int(TokMatchGroup)) || len(pr.Rules) == 0 { // group
+				if par, ok := pr.Par.(*Rule); ok && pr.Ast == NoAst && par.Ast != NoAst && par.IsGroup() && !prod[par.Nm] {
+					prod[par.Nm] = true // two-level group
+					chg = true
+				}
+				for _, k := range pr.Kids {
+					kpr := k.(*Rule)
+					if kpr.Off {
+						continue
+					}
+					if pr.Ast == AnchorAst {
+						chg = addAll(kids, ga.prod[kpr]) || chg
+					} else {
+						chg = addAll(prod, ga.prod[kpr]) || chg
+					}
+				}
+				continue
+			}
+			nr := len(pr.Rules)
+			subRules(pr, func(ri int, sr *Rule) {
+				sub := ga.prod[sr]
+				switch {
+				case pr.Ast == NoAst || pr.Ast == AddAst:
+					chg = addAll(prod, sub) || chg
+				case pr.Ast == SubAst && ri == nr-1:
+					chg = addAll(prod, sub) || chg
+				case pr.Ast == SubAst:
+					chg = addAll(kids, sub) || chg
+				case pr.Ast == AnchorFirstAst: // sub-rules go to parent when it is the same rule
+					chg = addAll(kids, sub) || chg
+					chg = addAll(prod, sub) || chg
+				default:
+					chg = addAll(kids, sub) || chg
+				}
+			})
+		}
+	}
+}
+
+// resolves returns true if given path element(s) from an Act Path can
+// resolve within the Ast of given rule, per ki FindPathTry
+func (ga *grammarAnalyzer) resolves(pr *Rule, path string) bool {
+	if strings.HasPrefix(path, "../") {
+		return true // parent is not known
+	}
+	path = strings.TrimSuffix(path, "...")
+	nm := pr.Nm
+	kids := ga.kids[pr]
+	for i, pe := range strings.Split(path, "/") {
+		if pe == "" || (i <= 1 && pe == nm) {
+			continue
+		}
+		if pe[0] == '[' {
+			return len(kids) > 0
+		}
+		if !kids[pe] {
+			return false
+		}
+		kpr, has := ga.names[pe]
+		if !has {
+			return true
+		}
+		nm = pe
+		kids = ga.kids[kpr]
+	}
+	return true
+}
+
+// actPaths reports Acts whose Path can never resolve in the Ast of their
+// rule, for rules that create an Ast node (otherwise the Path is relative
+// to the Ast of the parent, which is not known), and Acts on group rules
+func (ga *grammarAnalyzer) actPaths() {
+	ga.produced()
+	for _, pr := range ga.rules {
+		if len(pr.Acts) == 0 {
+			continue
+		}
+		if pr.HasFlag(int(TokMatchGroup)) || len(pr.Rules) == 0 {
+			ga.add(BadActPath, pr, "Acts on a group rule are never run")
+			continue
+		}
+		if pr.Ast == NoAst {
+			continue
+		}
+		for ai := range pr.Acts {
+			act := &pr.Acts[ai]
+			if act.Path == "" || act.Act == PushStack || act.Act == PopStack {
+				continue
+			}
+			sep := "|"
+			if strings.Contains(act.Path, "&") {
+				sep = "&"
+			}
+			ok := false
+			for _, p := range strings.Split(act.Path, sep) {
+				if ga.resolves(pr, p) {
+					ok = true
+					break
+				}
+			}
+			if !ok {
+				ga.add(BadActPath, pr, fmt.Sprintf("Act %v: path: %v can never resolve in its Ast", act.Act, act.Path))
+			}
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parse
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/goki/ki/indent"
+	"github.com/goki/ki/ki"
+	"github.com/goki/pi/lex"
+)
+
+// AstJSON is the JSON encoding of an Ast node, used by WriteAstJSON and
+// ReadAstJSON -- unlike the ki JSON encoding of the tree, it has just the
+// data of the nodes, in a stable form for use by external tools
+type AstJSON struct {
+
+	// name of the node, which is the rule that created it
+	Name string `desc:"name of the node, which is the rule that created it"`
+
+	// region in source lexical tokens
+	TokReg lex.Reg `desc:"region in source lexical tokens"`
+
+	// region in source file
+	SrcReg lex.Reg `desc:"region in source file"`
+
+	// source code for the node
+	Src string `desc:"source code for the node"`
+
+	// children of the node
+	Kids []*AstJSON `json:",omitempty" desc:"children of the node"`
+}
+
+// NewAstJSON returns the AstJSON encoding of given node and its children
+func NewAstJSON(ast *Ast) *AstJSON {
+	aj := &AstJSON{Name: ast.Nm, TokReg: ast.TokReg, SrcReg: ast.SrcReg, Src: ast.Src}
+	for _, k := range ast.Kids {
+		aj.Kids = append(aj.Kids, NewAstJSON(k.(*Ast)))
+	}
+	return aj
+}
+
+// SetAst sets given node and its children from the AstJSON,
+// replacing any existing children
+func (aj *AstJSON) SetAst(ast *Ast) {
+	updt := ast.UpdateStart()
+	ast.SetName(aj.Name)
+	ast.TokReg = aj.TokReg
+	ast.SrcReg = aj.SrcReg
+	ast.Src = aj.Src
+	ast.DeleteChildren(ki.DestroyKids)
+	for _, kj := range aj.Kids {
+		kj.SetAst(ast.AddNewChild(KiT_Ast, kj.Name).(*Ast))
+	}
+	ast.UpdateEnd(updt)
+}
+
+// WriteAstJSON writes the Ast starting at this node in JSON format,
+// with each node having its Name, TokReg, SrcReg, Src, and Kids
+func (ast *Ast) WriteAstJSON(w io.Writer, indent bool) error {
+	enc := json.NewEncoder(w)
+	if indent {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(NewAstJSON(ast))
+}
+
+// ReadAstJSON reads the Ast starting at this node in the JSON format
+// written by WriteAstJSON, replacing any existing children
+func (ast *Ast) ReadAstJSON(r io.Reader) error {
+	aj := &AstJSON{}
+	if err := json.NewDecoder(r).Decode(aj); err != nil {
+		return err
+	}
+	aj.SetAst(ast)
+	return nil
+}
+
+// SaveAstJSON saves the Ast starting at this node to given file, in the
+// JSON format of WriteAstJSON, e.g., for caching
+func (ast *Ast) SaveAstJSON(filename string) error {
+	fp, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	bw := bufio.NewWriter(fp)
+	if err := ast.WriteAstJSON(bw, false); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// OpenAstJSON opens the Ast starting at this node from given file,
+// in the JSON format of WriteAstJSON
+func (ast *Ast) OpenAstJSON(filename string) error {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	return ast.ReadAstJSON(bufio.NewReader(fp))
+}
+
+// WriteSexp writes the Ast starting at this node as an S-expression,
+// one node per line, indented by depth, in the form:
+//
+//	(Name (TokReg St.Ln St.Ch Ed.Ln Ed.Ch) (SrcReg ...) "Src" children...)
+//
+// where Src is a Go quoted string
+func (ast *Ast) WriteSexp(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	ast.writeSexp(bw, 0)
+	bw.WriteString("\n")
+	return bw.Flush()
+}
+
+func (ast *Ast) writeSexp(bw *bufio.Writer, depth int) {
+	if depth > 0 {
+		bw.WriteString("\n" + indent.Tabs(depth))
+	}
+	fmt.Fprintf(bw, "(%s (%d %d %d %d) (%d %d %d %d) %s", ast.Nm, ast.TokReg.St.Ln, ast.TokReg.St.Ch, ast.TokReg.Ed.Ln, ast.TokReg.Ed.Ch, ast.SrcReg.St.Ln, ast.SrcReg.St.Ch, ast.SrcReg.Ed.Ln, ast.SrcReg.Ed.Ch, strconv.Quote(ast.Src))
+	for _, k := range ast.Kids {
+		k.(*Ast).writeSexp(bw, depth+1)
+	}
+	bw.WriteString(")")
+}
+
+// ReadSexp reads the Ast starting at this node in the S-expression format
+// written by WriteSexp, replacing any existing children
+func (ast *Ast) ReadSexp(r io.Reader) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	sr := &sexpReader{str: string(b)}
+	aj, err := sr.node()
+	if err != nil {
+		return err
+	}
+	if tok := sr.next(); tok != "" {
+		return sr.errorf("unexpected input after end: %q", tok)
+	}
+	aj.SetAst(ast)
+	return nil
+}
+
+// sexpReader reads the tokens of an S-expression
+type sexpReader struct {
+	str string
+	pos int
+}
+
+func (sr *sexpReader) errorf(format string, args ...any) error {
+	return fmt.Errorf("parse.ReadSexp: at %d: %s", sr.pos, fmt.Sprintf(format, args...))
+}
+
+// next returns the next token: ( or ) or a quoted string or an atom,
+// or empty at the end
+func (sr *sexpReader) next() string {
+	for sr.pos < len(sr.str) && unicode.IsSpace(rune(sr.str[sr.pos])) {
+		sr.pos++
+	}
+	if sr.pos >= len(sr.str) {
+		return ""
+	}
+	st := sr.pos
+	switch sr.str[st] {
+	case '(', ')':
+		sr.pos++
+	case '"':
+		for sr.pos++; sr.pos < len(sr.str) && sr.str[sr.pos] != '"'; sr.pos++ {
+			if sr.str[sr.pos] == '\\' {
+				sr.pos++
+			}
+		}
+		sr.pos++
+		if sr.pos > len(sr.str) {
+			sr.pos = len(sr.str)
+		}
+	default:
+		for sr.pos < len(sr.str) && !unicode.IsSpace(rune(sr.str[sr.pos])) && !strings.ContainsRune("()\"", rune(sr.str[sr.pos])) {
+			sr.pos++
+		}
+	}
+	return sr.str[st:sr.pos]
+}
+
+// expect reads the next token and returns an error if it is not tok
+func (sr *sexpReader) expect(tok string) error {
+	if nt := sr.next(); nt != tok {
+		return sr.errorf("expected %q, got: %q", tok, nt)
+	}
+	return nil
+}
+
+// reg reads a region as a list of four numbers
+func (sr *sexpReader) reg() (lex.Reg, error) {
+	var reg lex.Reg
+	if err := sr.expect("("); err != nil {
+		return reg, err
+	}
+	for _, p := range []*int{&reg.St.Ln, &reg.St.Ch, &reg.Ed.Ln, &reg.Ed.Ch} {
+		tok := sr.next()
+		n, err := strconv.Atoi(tok)
+		if err != nil {
+			return reg, sr.errorf("expected number, got: %q", tok)
+		}
+		*p = n
+	}
+	return reg, sr.expect(")")
+}
+
+// node reads a node and its children
+func (sr *sexpReader) node() (*AstJSON, error) {
+	if err := sr.expect("("); err != nil {
+		return nil, err
+	}
+	aj := &AstJSON{Name: sr.next()}
+	if aj.Name == "" || strings.ContainsAny(aj.Name[:1], "()\"") {
+		return nil, sr.errorf("expected node name, got: %q", aj.Name)
+	}
+	var err error
+	if aj.TokReg, err = sr.reg(); err != nil {
+		return nil, err
+	}
+	if aj.SrcReg, err = sr.reg(); err != nil {
+		return nil, err
+	}
+	tok := sr.next()
+	if aj.Src, err = strconv.Unquote(tok); err != nil {
+		return nil, sr.errorf("expected quoted source, got: %q", tok)
+	}
+	for {
+		st := sr.pos
+		tok := sr.next()
+		if tok == ")" {
+			return aj, nil
+		}
+		if tok != "(" {
+			return nil, sr.errorf("expected child node or ), got: %q", tok)
+		}
+		sr.pos = st
+		kj, err := sr.node()
+		if err != nil {
+			return nil, err
+		}
+		aj.Kids = append(aj.Kids, kj)
+	}
+}
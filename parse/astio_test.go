@@ -0,0 +1,65 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parse_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/goki/pi/parse"
+)
+
+func TestAstEncode(t *testing.T) {
+	fs := parseGo(t, "testdata/decls.go")
+	var tree bytes.Buffer
+	fs.Ast.WriteTree(&tree, 0)
+
+	var js bytes.Buffer
+	if err := fs.Ast.WriteAstJSON(&js, false); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"Name":"Ast","TokReg":{"St":{"Ln":0,"Ch":0},"Ed":{"Ln":0,"Ch":0}},`; !strings.HasPrefix(js.String(), want) {
+		t.Errorf("JSON starts with: %.80s want %s", js.String(), want)
+	}
+	var sx bytes.Buffer
+	if err := fs.Ast.WriteSexp(&sx); err != nil {
+		t.Fatal(err)
+	}
+	if want := "(Ast (0 0 0 0) (0 0 0 0) \"\"\n\t(File"; !strings.HasPrefix(sx.String(), want) {
+		t.Errorf("S-expression starts with: %.80s want %s", sx.String(), want)
+	}
+	for _, enc := range []struct {
+		name string
+		data []byte
+		read func(ast *parse.Ast, r io.Reader) error
+	}{
+		{"JSON", js.Bytes(), (*parse.Ast).ReadAstJSON},
+		{"S-expression", sx.Bytes(), (*parse.Ast).ReadSexp},
+	} {
+		ast := &parse.Ast{}
+		ast.InitName(ast, "Tmp")
+		if err := enc.read(ast, bytes.NewReader(enc.data)); err != nil {
+			t.Errorf("read %v: %v", enc.name, err)
+			continue
+		}
+		var got bytes.Buffer
+		ast.WriteTree(&got, 0)
+		if got.String() != tree.String() {
+			t.Errorf("%v round trip tree differs:\n%v", enc.name, got.String())
+		}
+		if ast.ChildAst(0).ChildAst(0).SrcReg != fs.Ast.ChildAst(0).ChildAst(0).SrcReg {
+			t.Errorf("%v round trip SrcReg differs", enc.name)
+		}
+	}
+	for _, bad := range []string{"", "(Ast (0 0 0 0) (0 0 0 0) \"\"", "(Ast (0 0 0) (0 0 0 0) \"\")", "(Ast (0 0 0 0) (0 0 0 0) \"\") x", "(Ast (0 0 0 0) (0 0 0 0) x)"} {
+		ast := &parse.Ast{}
+		ast.InitName(ast, "Tmp")
+		if err := ast.ReadSexp(strings.NewReader(bad)); err == nil {
+			t.Errorf("read S-expression %q: expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parse
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/goki/pi/lex"
+)
+
+// MemoMaxEntries is the default maximum number of entries in a Memo table,
+// used if its MaxEntries is 0 -- each entry takes roughly 100 bytes plus
+// the match regions
+var MemoMaxEntries = 1000000
+
+// Memo is an optional packrat-style memo table of the results of matching
+// rules, for both matches and non-matches, keyed by the rule, the scope
+// region, and the top of the parser Stack (for StackMatch rules), so each
+// rule is matched at most once for a given scope, which bounds the time for
+// pathological inputs such as long chains of expressions.  As with the
+// NonMatches cache, the table is cleared at each EOS (see State
+// ResetNonMatches), because ChgToken actions retag tokens during parsing,
+// which can make earlier results stale.  It is also cleared if it reaches
+// MaxEntries within one statement.
+type Memo struct {
+
+	// use the memo table while parsing
+	On bool `desc:"use the memo table while parsing"`
+
+	// maximum number of entries, after which the table is cleared to limit memory use -- 0 = MemoMaxEntries
+	MaxEntries int `desc:"maximum number of entries, after which the table is cleared to limit memory use -- 0 = MemoMaxEntries"`
+
+	// the table of match results
+	Table map[MemoKey]MemoEntry `view:"-" json:"-" desc:"the table of match results"`
+
+	// statistics on the use of the table
+	Stats MemoStats `desc:"statistics on the use of the table"`
+}
+
+// MemoKey is the key for the Memo table
+type MemoKey struct {
+
+	// rule being matched
+	Rule *Rule `desc:"rule being matched"`
+
+	// scope the rule is matched within
+	Scope lex.Reg `desc:"scope the rule is matched within"`
+
+	// top of the parser stack
+	Stack string `desc:"top of the parser stack"`
+}
+
+// MemoEntry is the result of matching a rule, in the Memo table
+type MemoEntry struct {
+
+	// true if the rule matched
+	Match bool `desc:"true if the rule matched"`
+
+	// scope returned by the match
+	Scope lex.Reg `desc:"scope returned by the match"`
+
+	// match regions
+	Regs Matches `desc:"match regions"`
+}
+
+// MemoStats are statistics on the use of a Memo table
+type MemoStats struct {
+
+	// number of matches found in the table
+	Hits int `desc:"number of matches found in the table"`
+
+	// number of matches not found in the table, which were added
+	Misses int `desc:"number of matches not found in the table, which were added"`
+
+	// number of entries added for rules that did not match
+	NonMatches int `desc:"number of entries added for rules that did not match"`
+
+	// number of times the table was cleared on reaching the maximum entries (not counting the clearing at each EOS)
+	Clears int `desc:"number of times the table was cleared on reaching the maximum entries (not counting the clearing at each EOS)"`
+
+	// maximum number of entries in the table
+	MaxSize int `desc:"maximum number of entries in the table"`
+}
+
+// Reset clears the table and stats, at the start of parsing
+func (mm *Memo) Reset() {
+	mm.Table = nil
+	mm.Stats = MemoStats{}
+}
+
+// Clear clears the table, keeping the stats -- done at each EOS
+func (mm *Memo) Clear() {
+	if len(mm.Table) > 0 {
+		mm.Table = nil
+	}
+}
+
+// Get returns the entry for given key, if in the table
+func (mm *Memo) Get(key MemoKey) (MemoEntry, bool) {
+	me, has := mm.Table[key]
+	if has {
+		mm.Stats.Hits++
+	} else {
+		mm.Stats.Misses++
+	}
+	return me, has
+}
+
+// Add adds the entry for given key, clearing the table first if full
+func (mm *Memo) Add(key MemoKey, me MemoEntry) {
+	mx := mm.MaxEntries
+	if mx == 0 {
+		mx = MemoMaxEntries
+	}
+	if mm.Table == nil || len(mm.Table) >= mx {
+		if mm.Table != nil {
+			mm.Stats.Clears++
+		}
+		mm.Table = make(map[MemoKey]MemoEntry)
+	}
+	mm.Table[key] = me
+	if !me.Match {
+		mm.Stats.NonMatches++
+	}
+	if sz := len(mm.Table); sz > mm.Stats.MaxSize {
+		mm.Stats.MaxSize = sz
+	}
+}
+
+// HitRate returns the proportion of lookups found in the table
+func (ms *MemoStats) HitRate() float64 {
+	n := ms.Hits + ms.Misses
+	if n == 0 {
+		return 0
+	}
+	return float64(ms.Hits) / float64(n)
+}
+
+// Add adds the counts from the other stats, with the max of MaxSize
+func (ms *MemoStats) Add(os *MemoStats) {
+	ms.Hits += os.Hits
+	ms.Misses += os.Misses
+	ms.NonMatches += os.NonMatches
+	ms.Clears += os.Clears
+	if os.MaxSize > ms.MaxSize {
+		ms.MaxSize = os.MaxSize
+	}
+}
+
+// String is fmt.Stringer
+func (ms MemoStats) String() string {
+	return fmt.Sprintf("hits: %d misses: %d (%.1f%% hits) non-matches: %d clears: %d max size: %d", ms.Hits, ms.Misses, 100*ms.HitRate(), ms.NonMatches, ms.Clears, ms.MaxSize)
+}
+
+// MemoTotal accumulates the Memo stats over all parsing, e.g., for
+// a run over many files -- see AddMemoTotal
+var MemoTotal MemoStats
+
+// memoTotalMu protects MemoTotal
+var memoTotalMu sync.Mutex
+
+// AddMemoTotal adds given stats to the MemoTotal, under a mutex lock
+func AddMemoTotal(ms *MemoStats) {
+	memoTotalMu.Lock()
+	MemoTotal.Add(ms)
+	memoTotalMu.Unlock()
+}
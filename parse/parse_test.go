@@ -0,0 +1,32 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parse_test
+
+import (
+	"testing"
+
+	"github.com/goki/pi/filecat"
+	_ "github.com/goki/pi/langs/golang"
+	"github.com/goki/pi/pi"
+)
+
+func init() {
+	pi.LangSupport.OpenStd()
+}
+
+// parseGo returns the state of given Go source file, lexed and parsed
+// with the standard Go parser, for the tests on its Ast
+func parseGo(t testing.TB, fname string) *pi.FileState {
+	t.Helper()
+	lp, _ := pi.LangSupport.Props(filecat.Go)
+	pr := lp.Lang.Parser()
+	fs := pi.NewFileState()
+	if err := fs.Src.OpenFile(fname); err != nil {
+		t.Fatal(err)
+	}
+	pr.LexAll(fs)
+	pr.ParseAll(fs)
+	return fs
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package parsetest checks the output of a pi.Parser on a directory of source
+snippets against stored golden files, so that changes to a grammar do not
+silently break constructs that parsed before.  The output for each snippet
+is the Ast from WriteTree (only if the parser has parse rules -- languages
+such as markdown and tex only have a lexer), followed by the lexed tokens for
+each line, as tagged by the lexer and updated by the parser, each as
+[St:Ed:Token]"src" with the nesting depth as a +D prefix on the Token,
+followed by any errors.
+
+The update arg to Check and Dir writes the golden files from the current
+output instead, e.g., after adding a snippet, for review of the changes.
+It is typically set by an -update flag registered in the _test.go file:
+
+	var update = flag.Bool("update", false, "update parsetest golden files")
+*/
+package parsetest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goki/pi/filecat"
+	"github.com/goki/pi/pi"
+)
+
+// GoldenExt is the extension added to a source file name for its golden file
+var GoldenExt = ".golden"
+
+// Output returns the output for given source, which is compared against the
+// golden file: the Ast tree, if the parser has parse rules, the tokens for
+// each line, and any errors.  The parser must already be initialized,
+// e.g., by InitAll.
+func Output(pr *pi.Parser, src []byte, fname string, sup filecat.Supported) string {
+	fs := pi.NewFileState()
+	fs.Src.InitFromString(string(src), fname, sup)
+	fs.LexState.Filename = fname
+	pr.LexAll(fs)
+	hasPar := pr.Parser.HasChildren()
+	if hasPar {
+		pr.ParseAll(fs)
+	}
+
+	var b bytes.Buffer
+	if hasPar {
+		b.WriteString("// Ast\n")
+		fs.Ast.WriteTree(&b, 0)
+		b.WriteString("\n")
+	}
+	b.WriteString("// Tokens\n")
+	for ln := 0; ln < fs.Src.NLines(); ln++ {
+		ll := fs.Src.LexLine(ln)
+		if len(ll) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%d:", ln+1)
+		for _, lx := range ll {
+			fmt.Fprintf(&b, " [%d:%d:%v]%q", lx.St, lx.Ed, lx.Tok, string(lx.Src(fs.Src.Lines[ln])))
+		}
+		b.WriteString("\n")
+	}
+	if fs.LexHasErrs() || fs.PassTwoHasErrs() || fs.ParseHasErrs() {
+		b.WriteString("\n// Errors\n")
+		for _, er := range fs.LexState.Errs {
+			fmt.Fprintf(&b, "%v\n", er.Error())
+		}
+		for _, er := range fs.TwoState.Errs {
+			fmt.Fprintf(&b, "%v\n", er.Error())
+		}
+		fs.ParseState.Errs.Sort()
+		for _, er := range fs.ParseState.Errs {
+			fmt.Fprintf(&b, "%v\n", er.Error())
+		}
+	}
+	return b.String()
+}
+
+// Check parses given source file and compares the Output with its golden
+// file (the file name plus GoldenExt), reporting the first differing line
+// as a test error -- if update is set, the golden file is written instead.
+func Check(t testing.TB, pr *pi.Parser, fname string, sup filecat.Supported, update bool) {
+	t.Helper()
+	src, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := Output(pr, src, filepath.Base(fname), sup)
+	gfn := fname + GoldenExt
+	if update {
+		if err := os.WriteFile(gfn, []byte(out), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	gold, err := os.ReadFile(gfn)
+	if err != nil {
+		t.Errorf("%v: no golden file -- run with -update to create it: %v", fname, err)
+		return
+	}
+	if out == string(gold) {
+		return
+	}
+	ol := strings.Split(out, "\n")
+	gl := strings.Split(string(gold), "\n")
+	for i := 0; i < len(ol) || i < len(gl); i++ {
+		var o, g string
+		if i < len(ol) {
+			o = ol[i]
+		}
+		if i < len(gl) {
+			g = gl[i]
+		}
+		if o != g {
+			t.Errorf("%v: output differs from %v at line %d:\n got: %v\nwant: %v", fname, filepath.Base(gfn), i+1, o, g)
+			return
+		}
+	}
+}
+
+// Dir runs Check on each file in given directory having one of the given
+// extensions (e.g., ".go"), or all files other than golden files if none,
+// as a sub-test named by the file.  If update is set, the golden files are
+// written instead.
+func Dir(t *testing.T, pr *pi.Parser, dir string, sup filecat.Supported, update bool, exts ...string) {
+	t.Helper()
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, de := range des {
+		fn := de.Name()
+		if de.IsDir() || strings.HasSuffix(fn, GoldenExt) {
+			continue
+		}
+		if len(exts) > 0 {
+			ext := filepath.Ext(fn)
+			has := false
+			for _, ex := range exts {
+				if ex == ext {
+					has = true
+					break
+				}
+			}
+			if !has {
+				continue
+			}
+		}
+		t.Run(fn, func(t *testing.T) {
+			Check(t, pr, filepath.Join(dir, fn), sup, update)
+		})
+	}
+}
@@ -0,0 +1,409 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parse
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// RuleProfile has the profiling counters for one rule, collected by Profile
+type RuleProfile struct {
+
+	// the rule
+	Rule *Rule `desc:"the rule"`
+
+	// number of times the rule was matched
+	Attempts int `desc:"number of times the rule was matched"`
+
+	// number of times the rule matched
+	Matches int `desc:"number of times the rule matched"`
+
+	// number of attempts answered from the Matches, NonMatches or Memo caches
+	Cached int `desc:"number of attempts answered from the Matches, NonMatches or Memo caches"`
+
+	// number of times the exclusion tokens were checked, after the rule otherwise matched
+	Excludes int `desc:"number of times the exclusion tokens were checked, after the rule otherwise matched"`
+
+	// number of times the exclusion tokens matched, so the rule did not match
+	Excluded int `desc:"number of times the exclusion tokens matched, so the rule did not match"`
+
+	// total time spent matching the rule, including its sub-rules
+	Time time.Duration `desc:"total time spent matching the rule, including its sub-rules"`
+
+	// time spent matching the rule itself, excluding the time in its sub-rules
+	Self time.Duration `desc:"time spent matching the rule itself, excluding the time in its sub-rules"`
+
+	// number of calls to the rule in progress, so recursive calls are only counted once in Time
+	active int
+}
+
+// MatchRate returns the proportion of attempts that matched
+func (rp *RuleProfile) MatchRate() float64 {
+	if rp.Attempts == 0 {
+		return 0
+	}
+	return float64(rp.Matches) / float64(rp.Attempts)
+}
+
+// Add adds the counts from the other profile for the same rule
+func (rp *RuleProfile) Add(op *RuleProfile) {
+	rp.Attempts += op.Attempts
+	rp.Matches += op.Matches
+	rp.Cached += op.Cached
+	rp.Excludes += op.Excludes
+	rp.Excluded += op.Excluded
+	rp.Time += op.Time
+	rp.Self += op.Self
+}
+
+// profNode is a node in the tree of the rule matching calls, with the
+// counts for the rule when called from the path of rules to it
+type profNode struct {
+	rule     *Rule
+	par      *profNode
+	kids     map[*Rule]*profNode
+	attempts int
+	matches  int
+	self     time.Duration
+}
+
+// kid returns the child node for given rule, making it if needed
+func (pn *profNode) kid(pr *Rule) *profNode {
+	if kn, has := pn.kids[pr]; has {
+		return kn
+	}
+	if pn.kids == nil {
+		pn.kids = make(map[*Rule]*profNode)
+	}
+	kn := &profNode{rule: pr, par: pn}
+	pn.kids[pr] = kn
+	return kn
+}
+
+// add adds the counts from the other tree into this one
+func (pn *profNode) add(on *profNode) {
+	pn.attempts += on.attempts
+	pn.matches += on.matches
+	pn.self += on.self
+	for pr, ok := range on.kids {
+		pn.kid(pr).add(ok)
+	}
+}
+
+// profFrame is a rule matching call in progress
+type profFrame struct {
+	start time.Time
+	sub   time.Duration // time in sub-rule calls
+}
+
+// Profile collects counters for each rule while parsing: the number of
+// match attempts, matches, cached results and exclusion checks, and the
+// time spent, for finding the rules in a grammar to reorder or to give an
+// OptTokMap or FirstTokMap.  The counts are also kept for each path of rules
+// in the matching calls, for a pprof profile (WritePprof).
+type Profile struct {
+
+	// collect the profile while parsing
+	On bool `desc:"collect the profile while parsing"`
+
+	// counters for each rule
+	Rules map[*Rule]*RuleProfile `json:"-" desc:"counters for each rule"`
+
+	// tree of matching calls, for the pprof profile
+	root *profNode
+
+	// current node in the tree of matching calls
+	cur *profNode
+
+	// stack of matching calls in progress
+	frames []profFrame
+}
+
+// Reset clears the profile, at the start of parsing
+func (pp *Profile) Reset() {
+	pp.Rules = nil
+	pp.root = nil
+	pp.cur = nil
+	pp.frames = nil
+}
+
+// Rule returns the counters for given rule, making them if needed
+func (pp *Profile) Rule(pr *Rule) *RuleProfile {
+	if pp.Rules == nil {
+		pp.Rules = make(map[*Rule]*RuleProfile)
+	}
+	rp, has := pp.Rules[pr]
+	if !has {
+		rp = &RuleProfile{Rule: pr}
+		pp.Rules[pr] = rp
+	}
+	return rp
+}
+
+// Start is called at the start of matching given rule
+func (pp *Profile) Start(pr *Rule) {
+	if pp.root == nil {
+		pp.root = &profNode{}
+	}
+	if pp.cur == nil {
+		pp.cur = pp.root
+	}
+	pp.cur = pp.cur.kid(pr)
+	pp.Rule(pr).active++
+	pp.frames = append(pp.frames, profFrame{start: time.Now()})
+}
+
+// End is called at the end of matching given rule, with the result
+func (pp *Profile) End(pr *Rule, match bool) {
+	nf := len(pp.frames)
+	fr := pp.frames[nf-1]
+	pp.frames = pp.frames[:nf-1]
+	el := time.Since(fr.start)
+	if nf > 1 {
+		pp.frames[nf-2].sub += el
+	}
+	self := el - fr.sub
+	rp := pp.Rule(pr)
+	rp.active--
+	rp.Attempts++
+	rp.Self += self
+	if rp.active == 0 {
+		rp.Time += el
+	}
+	pn := pp.cur
+	pn.attempts++
+	pn.self += self
+	if match {
+		rp.Matches++
+		pn.matches++
+	}
+	pp.cur = pn.par
+}
+
+// CacheHit records that the result of matching given rule was cached
+func (pp *Profile) CacheHit(pr *Rule) {
+	if !pp.On {
+		return
+	}
+	pp.Rule(pr).Cached++
+}
+
+// Exclude records a check of the exclusion tokens of given rule, and
+// whether they matched
+func (pp *Profile) Exclude(pr *Rule, excl bool) {
+	if !pp.On {
+		return
+	}
+	rp := pp.Rule(pr)
+	rp.Excludes++
+	if excl {
+		rp.Excluded++
+	}
+}
+
+// Add adds the counts from the other profile, e.g., for another file
+// parsed with the same rules
+func (pp *Profile) Add(op *Profile) {
+	for pr, orp := range op.Rules {
+		pp.Rule(pr).Add(orp)
+	}
+	if op.root == nil {
+		return
+	}
+	if pp.root == nil {
+		pp.root = &profNode{}
+	}
+	pp.root.add(op.root)
+}
+
+// Sorted returns the counters for each rule, sorted by the time spent in
+// the rule itself, and then by attempts, most first
+func (pp *Profile) Sorted() []*RuleProfile {
+	rps := make([]*RuleProfile, 0, len(pp.Rules))
+	for _, rp := range pp.Rules {
+		rps = append(rps, rp)
+	}
+	sort.Slice(rps, func(i, j int) bool {
+		ri, rj := rps[i], rps[j]
+		if ri.Self != rj.Self {
+			return ri.Self > rj.Self
+		}
+		if ri.Attempts != rj.Attempts {
+			return ri.Attempts > rj.Attempts
+		}
+		return ri.Rule.Nm < rj.Rule.Nm
+	})
+	return rps
+}
+
+// WriteTable writes a table of the counters for each rule, in Sorted
+// order, limited to the first n rules if n > 0
+func (pp *Profile) WriteTable(w io.Writer, n int) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Rule\tAttempts\tMatches\tMatch%%\tCached\tExcludes\tExcluded\tTime\tSelf\t\n")
+	for i, rp := range pp.Sorted() {
+		if n > 0 && i >= n {
+			break
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%d\t%d\t%d\t%v\t%v\t\n", rp.Rule.Nm, rp.Attempts, rp.Matches, 100*rp.MatchRate(), rp.Cached, rp.Excludes, rp.Excluded, rp.Time.Round(time.Microsecond), rp.Self.Round(time.Microsecond))
+	}
+	return tw.Flush()
+}
+
+// WritePprof writes the profile in the gzipped protocol buffer format of
+// pprof (github.com/google/pprof), with the attempts, matches and self time
+// of the rules for each path of rules in the matching calls, so it can be
+// viewed with: go tool pprof -http=: file
+func (pp *Profile) WritePprof(w io.Writer) error {
+	var pb protoBuf
+	strs := map[string]int{"": 0}
+	stab := []string{""}
+	str := func(s string) int64 {
+		if i, has := strs[s]; has {
+			return int64(i)
+		}
+		strs[s] = len(stab)
+		stab = append(stab, s)
+		return int64(len(stab) - 1)
+	}
+	valueType := func(field int, typ, unit string) {
+		var vt protoBuf
+		vt.int(1, str(typ))
+		vt.int(2, str(unit))
+		pb.msg(field, &vt)
+	}
+	valueType(1, "attempts", "count")
+	valueType(1, "matches", "count")
+	valueType(1, "self", "nanoseconds")
+
+	ids := make(map[*Rule]uint64)
+	var rules []*Rule
+	var stack []uint64
+	var walk func(pn *profNode)
+	walk = func(pn *profNode) {
+		if pn.rule != nil {
+			id, has := ids[pn.rule]
+			if !has {
+				id = uint64(len(rules) + 1)
+				ids[pn.rule] = id
+				rules = append(rules, pn.rule)
+			}
+			stack = append(stack, id)
+			if pn.attempts > 0 {
+				var sm protoBuf
+				locs := make([]uint64, len(stack))
+				for i, lid := range stack {
+					locs[len(stack)-1-i] = lid // leaf first
+				}
+				sm.packed(1, locs)
+				sm.packed(2, []uint64{uint64(pn.attempts), uint64(pn.matches), uint64(pn.self)})
+				pb.msg(2, &sm)
+			}
+		}
+		kids := make([]*profNode, 0, len(pn.kids))
+		for _, kn := range pn.kids {
+			kids = append(kids, kn)
+		}
+		sort.Slice(kids, func(i, j int) bool { return kids[i].rule.Nm < kids[j].rule.Nm })
+		for _, kn := range kids {
+			walk(kn)
+		}
+		if pn.rule != nil {
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if pp.root != nil {
+		walk(pp.root)
+	}
+	for i := range rules {
+		id := int64(i + 1)
+		var ln, loc protoBuf
+		ln.int(1, id) // function id
+		loc.int(1, id)
+		loc.msg(4, &ln)
+		pb.msg(4, &loc)
+	}
+	for i, pr := range rules {
+		var fn protoBuf
+		fn.int(1, int64(i+1))
+		fn.int(2, str(pr.Nm))
+		fn.int(3, str(pr.Path()))
+		pb.msg(5, &fn)
+	}
+	valueType(11, "matching", "count") // period type
+	for _, s := range stab {
+		pb.bytes(6, []byte(s))
+	}
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(pb.Bytes()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// protoBuf encodes protocol buffer fields, for WritePprof
+type protoBuf struct {
+	bytes.Buffer
+}
+
+func (pb *protoBuf) varint(v uint64) {
+	for v >= 0x80 {
+		pb.WriteByte(byte(v) | 0x80)
+		v >>= 7
+	}
+	pb.WriteByte(byte(v))
+}
+
+// int writes a varint field, skipping zero values
+func (pb *protoBuf) int(field int, v int64) {
+	if v == 0 {
+		return
+	}
+	pb.varint(uint64(field) << 3)
+	pb.varint(uint64(v))
+}
+
+// bytes writes a length-delimited field
+func (pb *protoBuf) bytes(field int, b []byte) {
+	pb.varint(uint64(field)<<3 | 2)
+	pb.varint(uint64(len(b)))
+	pb.Write(b)
+}
+
+// msg writes an embedded message field
+func (pb *protoBuf) msg(field int, m *protoBuf) {
+	pb.bytes(field, m.Bytes())
+}
+
+// packed writes a packed repeated varint field
+func (pb *protoBuf) packed(field int, vs []uint64) {
+	var p protoBuf
+	for _, v := range vs {
+		p.varint(v)
+	}
+	pb.bytes(field, p.Bytes())
+}
+
+// ProfileTotal accumulates the Profile over all parsing, e.g., for
+// a run over many files -- see AddProfileTotal
+var ProfileTotal Profile
+
+// profileTotalMu protects ProfileTotal
+var profileTotalMu sync.Mutex
+
+// AddProfileTotal adds given profile to the ProfileTotal, under a mutex lock
+func AddProfileTotal(pp *Profile) {
+	profileTotalMu.Lock()
+	ProfileTotal.Add(pp)
+	profileTotalMu.Unlock()
+}
@@ -0,0 +1,391 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parse
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/goki/ki/ki"
+)
+
+// Query is a compiled selector for finding nodes in the Ast, by the names
+// of the nodes (i.e., the parse rules that created them), their relationship
+// to other nodes, and their source.  The syntax is similar to CSS selectors:
+//
+//	FuncDecl          nodes named FuncDecl
+//	Func*             names matching glob pattern (path.Match)
+//	FuncDecl > Name   Name nodes that are direct children of a FuncDecl
+//	FuncDecl Name     Name nodes anywhere within a FuncDecl
+//	Name + Args       Args nodes immediately after a Name sibling
+//	Name ~ Args       Args nodes after a Name sibling
+//	Selector[Name=fmt]        having a child named Name with source fmt
+//	FuncCall[Src^=Print]      own source starts with Print
+//	Block, ForExpr    either selector
+//
+// Attributes in [] are a child name pattern, or Src for the node's own
+// source, optionally followed by an operator and a value, which may be
+// quoted: = equals, != no child equals, ^= starts with, $= ends with,
+// *= contains.  Without an operator, the child just has to exist.
+type Query struct {
+
+	// the query string that was compiled
+	Str string `desc:"the query string that was compiled"`
+
+	// alternative selectors, separated by , in the query -- a node matching any of them matches the query
+	Sels [][]QueryStep `desc:"alternative selectors, separated by , in the query -- a node matching any of them matches the query"`
+}
+
+// QueryCombs are the combinators relating a QueryStep to the previous one
+type QueryCombs int
+
+const (
+	// QueryDesc is a descendant of the previous step (space)
+	QueryDesc QueryCombs = iota
+
+	// QueryChild is a direct child of the previous step (>)
+	QueryChild
+
+	// QueryAdjacent is the sibling immediately after the previous step (+)
+	QueryAdjacent
+
+	// QuerySibling is any sibling after the previous step (~)
+	QuerySibling
+)
+
+// QueryStep is one step of a Query selector, matching a single node
+type QueryStep struct {
+
+	// glob pattern for the name of the node, * for any
+	Name string `desc:"glob pattern for the name of the node, * for any"`
+
+	// attributes that must also match
+	Attrs []QueryAttr `desc:"attributes that must also match"`
+
+	// relationship to the node matched by the previous step -- ignored for the first step
+	Comb QueryCombs `desc:"relationship to the node matched by the previous step -- ignored for the first step"`
+}
+
+// QueryAttr is an attribute condition in a QueryStep
+type QueryAttr struct {
+
+	// glob pattern for the name of a child node, or Src for the node itself
+	Key string `desc:"glob pattern for the name of a child node, or Src for the node itself"`
+
+	// operator: one of = != ^= $= *=, or empty to just require the child
+	Op string `desc:"operator: one of = != ^= $= *=, or empty to just require the child"`
+
+	// value to compare the source with
+	Val string `desc:"value to compare the source with"`
+}
+
+// NewQuery returns a new compiled Query for given query string,
+// or an error if it is not valid
+func NewQuery(str string) (*Query, error) {
+	q := &Query{Str: str}
+	qp := queryParser{str: str}
+	for {
+		sel, err := qp.selector()
+		if err != nil {
+			return nil, err
+		}
+		q.Sels = append(q.Sels, sel)
+		qp.skipSpace()
+		if qp.pos >= len(qp.str) {
+			break
+		}
+		if qp.str[qp.pos] != ',' {
+			return nil, qp.errorf("expected , or end of query")
+		}
+		qp.pos++
+	}
+	return q, nil
+}
+
+// Match returns true if given node matches the query
+func (q *Query) Match(ast *Ast) bool {
+	for _, sel := range q.Sels {
+		if matchSel(ast, sel) {
+			return true
+		}
+	}
+	return false
+}
+
+// Find returns the nodes matching the query within the given node,
+// including itself, in tree order -- their source regions are in SrcReg
+func (q *Query) Find(ast *Ast) []*Ast {
+	var asts []*Ast
+	ast.FuncDownMeFirst(0, nil, func(k ki.Ki, level int, d any) bool {
+		if ka := k.(*Ast); q.Match(ka) {
+			asts = append(asts, ka)
+		}
+		return ki.Continue
+	})
+	return asts
+}
+
+// FindFirst returns the first node matching the query within the given
+// node, including itself, in tree order, or nil if none
+func (q *Query) FindFirst(ast *Ast) *Ast {
+	var fa *Ast
+	ast.FuncDownMeFirst(0, nil, func(k ki.Ki, level int, d any) bool {
+		if fa != nil {
+			return ki.Break
+		}
+		if ka := k.(*Ast); q.Match(ka) {
+			fa = ka
+			return ki.Break
+		}
+		return ki.Continue
+	})
+	return fa
+}
+
+// Query returns the nodes within this one, including itself, that match
+// given query string (see Query for the syntax), in tree order
+func (ast *Ast) Query(str string) ([]*Ast, error) {
+	q, err := NewQuery(str)
+	if err != nil {
+		return nil, err
+	}
+	return q.Find(ast), nil
+}
+
+// matchSel returns true if node matches the last step of the selector,
+// and the earlier steps match the related nodes, with backtracking
+func matchSel(ast *Ast, sel []QueryStep) bool {
+	n := len(sel)
+	if n == 0 || !sel[n-1].Match(ast) {
+		return false
+	}
+	if n == 1 {
+		return true
+	}
+	prv := sel[:n-1]
+	switch sel[n-1].Comb {
+	case QueryChild:
+		par := ast.ParAst()
+		return par != nil && matchSel(par, prv)
+	case QueryDesc:
+		for par := ast.ParAst(); par != nil; par = par.ParAst() {
+			if matchSel(par, prv) {
+				return true
+			}
+		}
+	case QueryAdjacent:
+		sib := prevSibAst(ast)
+		return sib != nil && matchSel(sib, prv)
+	case QuerySibling:
+		for sib := prevSibAst(ast); sib != nil; sib = prevSibAst(sib) {
+			if matchSel(sib, prv) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// prevSibAst returns the previous sibling of node, or nil if none
+func prevSibAst(ast *Ast) *Ast {
+	par := ast.ParAst()
+	if par == nil {
+		return nil
+	}
+	idx, ok := ast.IndexInParent()
+	if !ok || idx == 0 {
+		return nil
+	}
+	return par.ChildAst(idx - 1)
+}
+
+// Match returns true if given node matches the name and attributes of the step
+func (qs *QueryStep) Match(ast *Ast) bool {
+	if !globMatch(qs.Name, ast.Nm) {
+		return false
+	}
+	for i := range qs.Attrs {
+		if !qs.Attrs[i].Match(ast) {
+			return false
+		}
+	}
+	return true
+}
+
+// Match returns true if given node matches the attribute
+func (qa *QueryAttr) Match(ast *Ast) bool {
+	if qa.Key == "Src" {
+		return qa.Op == "" || qa.MatchVal(ast.Src)
+	}
+	if qa.Op == "!=" {
+		for _, k := range ast.Kids {
+			if ka := k.(*Ast); globMatch(qa.Key, ka.Nm) && ka.Src == qa.Val {
+				return false
+			}
+		}
+		return true
+	}
+	for _, k := range ast.Kids {
+		if ka := k.(*Ast); globMatch(qa.Key, ka.Nm) && (qa.Op == "" || qa.MatchVal(ka.Src)) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchVal returns true if given source matches the value using the operator
+func (qa *QueryAttr) MatchVal(src string) bool {
+	switch qa.Op {
+	case "=":
+		return src == qa.Val
+	case "!=":
+		return src != qa.Val
+	case "^=":
+		return strings.HasPrefix(src, qa.Val)
+	case "$=":
+		return strings.HasSuffix(src, qa.Val)
+	case "*=":
+		return strings.Contains(src, qa.Val)
+	}
+	return false
+}
+
+// globMatch returns true if name matches glob pattern
+func globMatch(pat, nm string) bool {
+	if pat == "*" || pat == nm {
+		return true
+	}
+	ok, _ := path.Match(pat, nm)
+	return ok
+}
+
+// queryParser parses a query string
+type queryParser struct {
+	str string
+	pos int
+}
+
+func (qp *queryParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("parse.Query: %q at %d: %s", qp.str, qp.pos, fmt.Sprintf(format, args...))
+}
+
+func (qp *queryParser) skipSpace() bool {
+	st := qp.pos
+	for qp.pos < len(qp.str) && (qp.str[qp.pos] == ' ' || qp.str[qp.pos] == '\t' || qp.str[qp.pos] == '\n') {
+		qp.pos++
+	}
+	return qp.pos > st
+}
+
+// isNameChar returns true for characters in node names and glob patterns
+func isNameChar(c byte) bool {
+	return c == '_' || c == '*' || c == '?' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func (qp *queryParser) name() string {
+	st := qp.pos
+	for qp.pos < len(qp.str) && isNameChar(qp.str[qp.pos]) {
+		qp.pos++
+	}
+	return qp.str[st:qp.pos]
+}
+
+// selector parses steps up to the next , or end of query
+func (qp *queryParser) selector() ([]QueryStep, error) {
+	var sel []QueryStep
+	comb := QueryDesc
+	qp.skipSpace()
+	for {
+		st, err := qp.step()
+		if err != nil {
+			return nil, err
+		}
+		st.Comb = comb
+		sel = append(sel, st)
+		sp := qp.skipSpace()
+		if qp.pos >= len(qp.str) || qp.str[qp.pos] == ',' {
+			return sel, nil
+		}
+		switch qp.str[qp.pos] {
+		case '>':
+			comb = QueryChild
+		case '+':
+			comb = QueryAdjacent
+		case '~':
+			comb = QuerySibling
+		default:
+			if !sp {
+				return nil, qp.errorf("unexpected character: %q", qp.str[qp.pos])
+			}
+			comb = QueryDesc
+			continue
+		}
+		qp.pos++
+		qp.skipSpace()
+	}
+}
+
+// step parses a name followed by any attributes
+func (qp *queryParser) step() (QueryStep, error) {
+	st := QueryStep{Name: qp.name()}
+	if st.Name == "" {
+		if qp.pos < len(qp.str) && qp.str[qp.pos] == '[' {
+			st.Name = "*"
+		} else {
+			return st, qp.errorf("expected node name")
+		}
+	}
+	for qp.pos < len(qp.str) && qp.str[qp.pos] == '[' {
+		qp.pos++
+		qa, err := qp.attr()
+		if err != nil {
+			return st, err
+		}
+		st.Attrs = append(st.Attrs, qa)
+	}
+	return st, nil
+}
+
+// attr parses an attribute after the [, through the closing ]
+func (qp *queryParser) attr() (QueryAttr, error) {
+	qp.skipSpace()
+	qa := QueryAttr{Key: qp.name()}
+	if qa.Key == "" {
+		return qa, qp.errorf("expected attribute name")
+	}
+	qp.skipSpace()
+	for _, op := range []string{"=", "!=", "^=", "$=", "*="} {
+		if strings.HasPrefix(qp.str[qp.pos:], op) {
+			qa.Op = op
+			qp.pos += len(op)
+			break
+		}
+	}
+	if qa.Op != "" {
+		qp.skipSpace()
+		if qp.pos < len(qp.str) && qp.str[qp.pos] == '"' {
+			ed := strings.IndexByte(qp.str[qp.pos+1:], '"')
+			if ed < 0 {
+				return qa, qp.errorf("unterminated quoted value")
+			}
+			qa.Val = qp.str[qp.pos+1 : qp.pos+1+ed]
+			qp.pos += ed + 2
+		} else {
+			ed := strings.IndexByte(qp.str[qp.pos:], ']')
+			if ed < 0 {
+				return qa, qp.errorf("expected ]")
+			}
+			qa.Val = strings.TrimSpace(qp.str[qp.pos : qp.pos+ed])
+			qp.pos += ed
+		}
+		qp.skipSpace()
+	}
+	if qp.pos >= len(qp.str) || qp.str[qp.pos] != ']' {
+		return qa, qp.errorf("expected ]")
+	}
+	qp.pos++
+	return qa, nil
+}
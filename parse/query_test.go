@@ -0,0 +1,49 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/goki/pi/parse"
+)
+
+func TestQuery(t *testing.T) {
+	fs := parseGo(t, "testdata/decls.go")
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"FuncDecl > Name, MethDecl > Name", "Add@24:17 Hello@28:5"},
+		{"MethDecl Name[Src^=p]", "pt@24:6 pt@25:17 pt@25:32"},
+		{"Selector[Name=fmt] > FuncCall > Name", "Sprintf@29:12"},
+		{"Selector[Name!=fmt][Name!=pt][Name!=o] FuncCall", "ToUpper(nm)@29:36"},
+		{"Import + ImportAlias", `str "strings"@5:1`},
+		{"PackageSpec ~ Cons*", "const Pi = 3.14@8"},
+		{`[Src="x, y"] > *`, "x@11:1 y@11:4"},
+		{"Params > ParName[BasicType=string] > Name", "nm@28:11"},
+		{"Nothing", ""},
+	}
+	for _, tt := range tests {
+		asts, err := fs.Ast.Query(tt.query)
+		if err != nil {
+			t.Errorf("query %q: %v", tt.query, err)
+			continue
+		}
+		var ms []string
+		for _, ast := range asts {
+			ms = append(ms, ast.Src+"@"+ast.SrcReg.St.String())
+		}
+		if got := strings.Join(ms, " "); got != tt.want {
+			t.Errorf("query %q: got %q want %q", tt.query, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "FuncDecl >", "Name[Src=x", "Name[=x]", "Name,", "Name $ Args"} {
+		if _, err := parse.NewQuery(bad); err == nil {
+			t.Errorf("query %q: expected error", bad)
+		}
+	}
+}
@@ -33,6 +33,14 @@ var GuiActive = false
 // DepthLimit is the infinite recursion prevention cutoff
 var DepthLimit = 10000
 
+// BadStmtName and BadExprName are the names of the Ast nodes added for
+// the input skipped by error recovery (see State.Recover): BadStmt when
+// the skipped input ends at an EOS, and BadExpr otherwise
+var (
+	BadStmtName = "BadStmt"
+	BadExprName = "BadExpr"
+)
+
 // parse.Rule operates on the lexically-tokenized input, not the raw source.
 //
 // The overall strategy is pragmatically based on the current known form of
@@ -657,6 +665,15 @@ func (pr *Rule) StartParse(ps *State) *Rule {
 				ps.Error(cpos, "did not advance position -- need more rules to match current input -- skipping to next EOS", pr)
 				didErr = true
 			}
+			if ps.Recover && pr.RecoverBad(ps, parAst, lex.Reg{St: cpos}) {
+				ep, ok := ps.Src.NextTokenPos(ps.Pos) // skip the EOS
+				if !ok {
+					ps.GotoEof()
+					return nil
+				}
+				ps.Pos = ep
+				continue
+			}
 			cp, ok := ps.Src.NextTokenPos(ps.Pos)
 			if !ok {
 				ps.GotoEof()
@@ -805,7 +822,46 @@ func (pr *Rule) Match(ps *State, parAst *Ast, scope lex.Reg, depth int, optMap l
 		return false, scope, nil
 	}
 
-	if ps.IsNonMatch(scope, pr) {
+	if !ps.Profile.On {
+		return pr.MatchMemo(ps, parAst, scope, depth, optMap)
+	}
+	ps.Profile.Start(pr)
+	match, nscope, mpos := pr.MatchMemo(ps, parAst, scope, depth, optMap)
+	ps.Profile.End(pr, match)
+	return match, nscope, mpos
+}
+
+// MatchMemo attempts to match the rule using the Memo table if IsMemo,
+// returns true if it matches, and the match positions, along with any
+// update to the scope
+func (pr *Rule) MatchMemo(ps *State, parAst *Ast, scope lex.Reg, depth int, optMap lex.TokenMap) (bool, lex.Reg, Matches) {
+	if !pr.IsMemo(ps) {
+		return pr.MatchNoMemo(ps, parAst, scope, depth, optMap)
+	}
+	key := MemoKey{Rule: pr, Scope: scope, Stack: ps.Stack.Top()}
+	if me, has := ps.Memo.Get(key); has {
+		ps.Profile.CacheHit(pr)
+		return me.Match, me.Scope, me.Regs
+	}
+	match, nscope, mpos := pr.MatchNoMemo(ps, parAst, scope, depth, optMap)
+	ps.Memo.Add(key, MemoEntry{Match: match, Scope: nscope, Regs: mpos})
+	return match, nscope, mpos
+}
+
+// IsMemo returns true if the Memo table is used for matching this rule,
+// in which case it takes the place of the NonMatches cache -- rules with
+// only tokens are fast to match and are not memoized
+func (pr *Rule) IsMemo(ps *State) bool {
+	return ps.Memo.On && !pr.HasFlag(int(OnlyToks))
+}
+
+// MatchNoMemo attempts to match the rule without using the Memo table,
+// returns true if it matches, and the match positions, along with any
+// update to the scope
+func (pr *Rule) MatchNoMemo(ps *State, parAst *Ast, scope lex.Reg, depth int, optMap lex.TokenMap) (bool, lex.Reg, Matches) {
+	memo := pr.IsMemo(ps)
+	if !memo && ps.IsNonMatch(scope, pr) {
+		ps.Profile.CacheHit(pr)
 		return false, scope, nil
 	}
 
@@ -815,12 +871,8 @@ func (pr *Rule) Match(ps *State, parAst *Ast, scope lex.Reg, depth int, optMap l
 		}
 	}
 
-	// mprf := prof.Start("Match")
-	// defer mprf.End()
-	// Note: uncomment the following to see which rules are taking the most
+	// Note: turn on ps.Profile to see which rules are taking the most
 	// time -- very helpful for focusing effort on optimizing those rules.
-	// prf := prof.Start(pr.Nm)
-	// defer prf.End()
 
 	nr := len(pr.Rules)
 	if pr.HasFlag(int(TokMatchGroup)) || nr == 0 { // Group
@@ -830,6 +882,7 @@ func (pr *Rule) Match(ps *State, parAst *Ast, scope lex.Reg, depth int, optMap l
 	// prf := prof.Start("IsMatch")
 	if mst, match := ps.IsMatch(pr, scope); match {
 		// prf.End()
+		ps.Profile.CacheHit(pr)
 		return true, scope, mst.Regs
 	}
 	// prf.End()
@@ -845,17 +898,23 @@ func (pr *Rule) Match(ps *State, parAst *Ast, scope lex.Reg, depth int, optMap l
 		match, mpos = pr.MatchMixed(ps, parAst, scope, depth, optMap)
 	}
 	if !match {
-		ps.AddNonMatch(scope, pr)
+		if !memo {
+			ps.AddNonMatch(scope, pr)
+		}
 		return false, scope, nil
 	}
 
 	if len(pr.ExclFwd) > 0 || len(pr.ExclRev) > 0 {
 		ktpos := mpos[pr.ExclKeyIdx]
-		if pr.MatchExclude(ps, scope, ktpos, depth, optMap) {
+		excl := pr.MatchExclude(ps, scope, ktpos, depth, optMap)
+		ps.Profile.Exclude(pr, excl)
+		if excl {
 			if ps.Trace.On {
 				ps.Trace.Out(ps, pr, NoMatch, ktpos.St, scope, parAst, "Exclude criteria matched")
 			}
-			ps.AddNonMatch(scope, pr)
+			if !memo {
+				ps.AddNonMatch(scope, pr)
+			}
 			return false, scope, nil
 		}
 	}
@@ -1122,6 +1181,7 @@ func (pr *Rule) MatchGroup(ps *State, parAst *Ast, scope lex.Reg, depth int, opt
 	// prf := prof.Start("SubMatch")
 	if mst, match := ps.IsMatch(pr, scope); match {
 		// 	prf.End()
+		ps.Profile.CacheHit(pr)
 		return true, scope, mst.Regs
 	}
 	// prf.End()
@@ -1154,7 +1214,9 @@ func (pr *Rule) MatchGroup(ps *State, parAst *Ast, scope lex.Reg, depth int, opt
 			return true, nscope, mpos
 		}
 	}
-	ps.AddNonMatch(scope, pr)
+	if !pr.IsMemo(ps) {
+		ps.AddNonMatch(scope, pr)
+	}
 	return false, scope, nil
 }
 
@@ -1390,6 +1452,12 @@ func (pr *Rule) DoRules(ps *State, par *Rule, parAst *Ast, scope lex.Reg, mpos M
 		if subm == nil {
 			if !rr.Opt {
 				ps.Error(creg.St, fmt.Sprintf("required element: %v did not match input", rr.Rule.Name()), pr)
+				if ps.Recover && pr.RecoverBad(ps, useAst, creg) {
+					if ps.Trace.On {
+						ps.Trace.Out(ps, pr, Run, creg.St, creg, trcAst, fmt.Sprintf("%v: recovered from rule: %v to: %v", ri, rr.Rule.Name(), ps.Pos))
+					}
+					continue
+				}
 				valid = false
 				break
 			} else {
@@ -1408,6 +1476,35 @@ func (pr *Rule) DoRules(ps *State, par *Rule, parAst *Ast, scope lex.Reg, mpos M
 	return valid
 }
 
+// RecoverBad recovers from a failure to match at the start of given region,
+// in State.Recover mode, by skipping to the next EOS at the same depth, but
+// not past the end of the region if set, and adding an Ast node covering the
+// skipped tokens: BadStmt if they start a statement (after an EOS or brace)
+// and end at an EOS, and BadExpr otherwise.  The position is left at the
+// end of the skipped tokens.  Returns false if there is nothing to skip.
+func (pr *Rule) RecoverBad(ps *State, parAst *Ast, reg lex.Reg) bool {
+	stlx := ps.Src.LexAt(reg.St)
+	ep, ok := ps.Src.NextEos(reg.St, stlx.Tok.Depth)
+	if !ok || (reg.Ed != lex.PosZero && reg.Ed.IsLess(ep)) {
+		ep = reg.Ed
+	}
+	if !reg.St.IsLess(ep) {
+		return false
+	}
+	nm := BadExprName
+	if ps.Src.LexAtSafe(ep).Tok.Tok == token.EOS {
+		pp, ok := ps.Src.PrevTokenPos(reg.St)
+		if !ok {
+			nm = BadStmtName
+		} else if ptok := ps.Src.LexAt(pp).Tok.Tok; ptok == token.EOS || ptok == token.PunctGpLBrace {
+			nm = BadStmtName
+		}
+	}
+	ps.AddAst(parAst, nm, lex.Reg{St: reg.St, Ed: ep})
+	ps.Pos = ep
+	return true
+}
+
 // DoRulesRevBinExp reverse version of do rules for binary expression rule with
 // one key token in the middle -- we just pay attention to scoping rest of sub-rules
 // relative to that, and don't otherwise adjust scope or position.  In particular all
@@ -1776,9 +1873,40 @@ func (pr *Rule) WriteGrammar(writer io.Writer, depth int) {
 		if pr.Desc != "" {
 			fmt.Fprintf(writer, "%v// %v %v \n", ind, nmstr, pr.Desc)
 		}
+		astr := ""
+		switch pr.Ast {
+		case AddAst:
+			astr = "+Ast"
+		case SubAst:
+			astr = "_Ast"
+		case AnchorAst:
+			astr = ">Ast"
+		case AnchorFirstAst:
+			astr = ">1Ast"
+		}
+		optstr := ""
+		if pr.StackMatch != "" || pr.FirstTokMap || pr.OptTokMap {
+			optstr = "\t opts: "
+			if pr.StackMatch != "" {
+				optstr += "StackMatch: " + pr.StackMatch + "; "
+			}
+			if pr.FirstTokMap {
+				optstr += "FirstTokMap; "
+			}
+			if pr.OptTokMap {
+				optstr += "OptTokMap; "
+			}
+		}
 		if pr.IsGroup() {
-			fmt.Fprintf(writer, "%v%v {\n", ind, nmstr)
+			if pr.Rule == "" && astr == "" && optstr == "" {
+				fmt.Fprintf(writer, "%v%v {\n", ind, nmstr)
+			} else {
+				fmt.Fprintf(writer, "%v%v:\t%v\t%v%v {\n", ind, nmstr, pr.Rule, astr, optstr)
+			}
 			w := tabwriter.NewWriter(writer, 4, 4, 2, ' ', 0)
+			if len(pr.Acts) > 0 {
+				fmt.Fprintf(w, "%v--->Acts:%v\n", indent.Tabs(depth+1), pr.Acts.String())
+			}
 			for _, k := range pr.Kids {
 				pri := k.(*Rule)
 				pri.WriteGrammar(w, depth+1)
@@ -1786,18 +1914,7 @@ func (pr *Rule) WriteGrammar(writer io.Writer, depth int) {
 			w.Flush()
 			fmt.Fprintf(writer, "%v}\n", ind)
 		} else {
-			astr := ""
-			switch pr.Ast {
-			case AddAst:
-				astr = "+Ast"
-			case SubAst:
-				astr = "_Ast"
-			case AnchorAst:
-				astr = ">Ast"
-			case AnchorFirstAst:
-				astr = ">1Ast"
-			}
-			fmt.Fprintf(writer, "%v%v:\t%v\t%v\n", ind, nmstr, pr.Rule, astr)
+			fmt.Fprintf(writer, "%v%v:\t%v\t%v%v\n", ind, nmstr, pr.Rule, astr, optstr)
 			if len(pr.Acts) > 0 {
 				fmt.Fprintf(writer, "%v--->Acts:%v\n", ind, pr.Acts.String())
 			}
@@ -1805,6 +1922,51 @@ func (pr *Rule) WriteGrammar(writer io.Writer, depth int) {
 	}
 }
 
+// SetGrammar sets the rule from the grammar string written by WriteGrammar
+// after the rule name and colon, without any group { -- i.e., the Rule,
+// followed by optional Ast action and opts: StackMatch, FirstTokMap, OptTokMap.
+// Acts are set separately from their own line.
+func (pr *Rule) SetGrammar(str string) error {
+	pr.StackMatch = ""
+	pr.FirstTokMap = false
+	pr.OptTokMap = false
+	pr.Ast = NoAst
+	if oi := strings.Index(str, "opts: "); oi >= 0 {
+		for _, opt := range strings.Split(str[oi+len("opts: "):], ";") {
+			opt = strings.TrimSpace(opt)
+			switch {
+			case opt == "":
+			case opt == "FirstTokMap":
+				pr.FirstTokMap = true
+			case opt == "OptTokMap":
+				pr.OptTokMap = true
+			case strings.HasPrefix(opt, "StackMatch:"):
+				pr.StackMatch = strings.TrimSpace(strings.TrimPrefix(opt, "StackMatch:"))
+			default:
+				return fmt.Errorf("parse.Rule %v: unknown option: %v", pr.Nm, opt)
+			}
+		}
+		str = str[:oi]
+	}
+	str = strings.TrimSpace(str)
+	si := strings.LastIndexAny(str, " \t") // ast action is last, if present
+	switch str[si+1:] {
+	case "+Ast":
+		pr.Ast = AddAst
+	case "_Ast":
+		pr.Ast = SubAst
+	case ">Ast":
+		pr.Ast = AnchorAst
+	case ">1Ast":
+		pr.Ast = AnchorFirstAst
+	}
+	if pr.Ast != NoAst {
+		str = strings.TrimSpace(str[:si+1])
+	}
+	pr.Rule = str
+	return nil
+}
+
 var RuleProps = ki.Props{
 	"EnumType:Flag": ki.KiT_Flags,
 	// "CallMethods": ki.PropSlice{
@@ -21,6 +21,9 @@ type State struct {
 	// tracing for this parser
 	Trace TraceOpts `desc:"tracing for this parser"`
 
+	// if true, parsing continues after a required element of a rule fails to match, by skipping to the next EOS at the same depth and adding a BadStmt or BadExpr Ast node covering the skipped input -- keeps the Ast and symbols after errors
+	Recover bool `desc:"if true, parsing continues after a required element of a rule fails to match, by skipping to the next EOS at the same depth and adding a BadStmt or BadExpr Ast node covering the skipped input -- keeps the Ast and symbols after errors"`
+
 	// root of the Ast abstract syntax tree we're updating
 	Ast *Ast `desc:"root of the Ast abstract syntax tree we're updating"`
 
@@ -44,6 +47,12 @@ type State struct {
 
 	// [view: no-inline] stack for context-sensitive rules
 	Stack lex.Stack `view:"no-inline" desc:"stack for context-sensitive rules"`
+
+	// optional packrat memo table of rule match results, bounding parse time for pathological inputs
+	Memo Memo `view:"no-inline" desc:"optional packrat memo table of rule match results, bounding parse time for pathological inputs"`
+
+	// optional profile of the rule matching, with counters for each rule
+	Profile Profile `view:"no-inline" desc:"optional profile of the rule matching, with counters for each rule"`
 }
 
 // Init initializes the state at start of parsing
@@ -79,6 +88,8 @@ func (ps *State) AllocRules() {
 		}
 	}
 	ps.NonMatches = make(ScopeRuleSet, ntot*10)
+	ps.Memo.Reset()
+	ps.Profile.Reset()
 }
 
 // Error adds a parsing error at given lex token position
@@ -383,9 +394,11 @@ func (ps *State) IsNonMatch(scope lex.Reg, pr *Rule) bool {
 	return ps.NonMatches.Has(scope, pr)
 }
 
-// ResetNonMatches resets the non-match map -- do after every EOS
+// ResetNonMatches resets the non-match map, and clears the Memo table,
+// as ChgToken actions can retag tokens -- do after every EOS
 func (ps *State) ResetNonMatches() {
 	ps.NonMatches = make(ScopeRuleSet)
+	ps.Memo.Clear()
 }
 
 ///////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,153 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parse
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/goki/pi/lex"
+)
+
+// Visitor is called by Walk for each node in the Ast
+type Visitor interface {
+	// Visit is called before the children of the node -- return false
+	// to skip the children, and the PostVisit of the node
+	Visit(ast *Ast) bool
+
+	// PostVisit is called after the children of the node
+	PostVisit(ast *Ast)
+}
+
+// Walk calls the Visitor for given node and all of its children,
+// depth-first, with Visit called before and PostVisit after the children
+func Walk(ast *Ast, vis Visitor) {
+	if !vis.Visit(ast) {
+		return
+	}
+	for _, k := range ast.Kids {
+		Walk(k.(*Ast), vis)
+	}
+	vis.PostVisit(ast)
+}
+
+// VisitorFuncs is a Visitor using functions, either of which can be nil
+type VisitorFuncs struct {
+
+	// function called before the children of a node -- return false to skip them
+	Pre func(ast *Ast) bool `desc:"function called before the children of a node -- return false to skip them"`
+
+	// function called after the children of a node
+	Post func(ast *Ast) `desc:"function called after the children of a node"`
+}
+
+func (vf *VisitorFuncs) Visit(ast *Ast) bool {
+	if vf.Pre == nil {
+		return true
+	}
+	return vf.Pre(ast)
+}
+
+func (vf *VisitorFuncs) PostVisit(ast *Ast) {
+	if vf.Post != nil {
+		vf.Post(ast)
+	}
+}
+
+// NameVisitor is a Visitor that calls the function for the name of each
+// node, i.e., the parse rule that created it (e.g., FuncDecl), which is
+// the type of the node -- nodes without a function are visited,
+// and the function returns false to skip the children of its node.
+type NameVisitor map[string]func(ast *Ast) bool
+
+func (nv NameVisitor) Visit(ast *Ast) bool {
+	if fun, has := nv[ast.Nm]; has {
+		return fun(ast)
+	}
+	return true
+}
+
+func (nv NameVisitor) PostVisit(ast *Ast) {}
+
+// Rewriter records replacements of the source of Ast nodes, as a set of
+// non-overlapping edits of the source lines
+type Rewriter struct {
+
+	// source file of the Ast
+	Src *lex.File `desc:"source file of the Ast"`
+
+	// edits, sorted by position in the file
+	Edits []lex.FileEdit `desc:"edits, sorted by position in the file"`
+}
+
+// NewRewriter returns a new Rewriter for given source
+func NewRewriter(src *lex.File) *Rewriter {
+	return &Rewriter{Src: src}
+}
+
+// Replace replaces the source of given node with the text.
+// Returns an error if it overlaps a prior edit.
+func (rw *Rewriter) Replace(ast *Ast, text string) error {
+	return rw.ReplaceReg(ast.SrcReg, text)
+}
+
+// InsertBefore inserts the text before the source of given node
+func (rw *Rewriter) InsertBefore(ast *Ast, text string) error {
+	return rw.ReplaceReg(lex.Reg{St: ast.SrcReg.St, Ed: ast.SrcReg.St}, text)
+}
+
+// InsertAfter inserts the text after the source of given node
+func (rw *Rewriter) InsertAfter(ast *Ast, text string) error {
+	return rw.ReplaceReg(lex.Reg{St: ast.SrcReg.Ed, Ed: ast.SrcReg.Ed}, text)
+}
+
+// ReplaceReg replaces given region of the source with the text -- an empty
+// region inserts the text, before any other edit starting at that position.
+// Returns an error if it overlaps a prior edit.
+func (rw *Rewriter) ReplaceReg(reg lex.Reg, text string) error {
+	ins := reg.St == reg.Ed
+	i := sort.Search(len(rw.Edits), func(i int) bool {
+		er := rw.Edits[i].Reg
+		return reg.St.IsLess(er.St) || (ins && reg.St == er.St && er.St != er.Ed)
+	})
+	if i > 0 && reg.St.IsLess(rw.Edits[i-1].Reg.Ed) {
+		return fmt.Errorf("parse.Rewriter: edit at: %v overlaps prior edit at: %v", reg.St, rw.Edits[i-1].Reg.St)
+	}
+	if i < len(rw.Edits) && rw.Edits[i].Reg.St.IsLess(reg.Ed) {
+		return fmt.Errorf("parse.Rewriter: edit at: %v overlaps prior edit at: %v", reg.St, rw.Edits[i].Reg.St)
+	}
+	ed := lex.FileEdit{FileReg: lex.FileReg{Filename: rw.Src.Filename, Reg: reg}, Text: text}
+	rw.Edits = append(rw.Edits, lex.FileEdit{})
+	copy(rw.Edits[i+1:], rw.Edits[i:])
+	rw.Edits[i] = ed
+	return nil
+}
+
+// Apply returns the source lines with the edits applied -- the Src
+// is not changed
+func (rw *Rewriter) Apply() [][]rune {
+	return lex.ApplyEdits(rw.Src.Lines, rw.Edits)
+}
+
+// Rewrite walks given Ast calling fun for each node, and replaces the
+// source of the node with the text returned if true, without visiting
+// its children.  Returns the edits of the source lines, and the first error
+// from an edit that could not be made (e.g., from a node region that overlaps
+// a prior edit), in which case that edit is skipped but the others are kept.
+func Rewrite(ast *Ast, src *lex.File, fun func(ast *Ast) (string, bool)) ([]lex.FileEdit, error) {
+	rw := NewRewriter(src)
+	var rerr error
+	Walk(ast, &VisitorFuncs{Pre: func(ast *Ast) bool {
+		text, ok := fun(ast)
+		if !ok {
+			return true
+		}
+		if err := rw.Replace(ast, text); err != nil && rerr == nil { // children skipped, so only overlaps for bad regions
+			rerr = err
+		}
+		return false
+	}})
+	return rw.Edits, rerr
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/goki/pi/lex"
+	"github.com/goki/pi/parse"
+)
+
+func TestWalkRewrite(t *testing.T) {
+	fs := parseGo(t, "testdata/decls.go")
+
+	var funcs []string
+	parse.Walk(&fs.Ast, parse.NameVisitor{
+		"FuncDecl": func(ast *parse.Ast) bool { funcs = append(funcs, ast.ChildAst(0).Src); return false },
+		"MethDecl": func(ast *parse.Ast) bool { funcs = append(funcs, ast.ChildAst(1).Src); return false },
+		"Types":    func(ast *parse.Ast) bool { return false },
+	})
+	if got := strings.Join(funcs, " "); got != "Add Hello" {
+		t.Errorf("NameVisitor funcs: %q", got)
+	}
+	var path []string
+	maxDepth := 0
+	parse.Walk(&fs.Ast, &parse.VisitorFuncs{
+		Pre: func(ast *parse.Ast) bool {
+			if ast.Nm == "Block" {
+				return false
+			}
+			path = append(path, ast.Nm)
+			if len(path) > maxDepth {
+				maxDepth = len(path)
+			}
+			return true
+		},
+		Post: func(ast *parse.Ast) {
+			if path[len(path)-1] != ast.Nm {
+				t.Errorf("PostVisit %v out of order: %v", ast.Nm, path)
+			}
+			path = path[:len(path)-1]
+		},
+	})
+	if len(path) != 0 || maxDepth != 8 {
+		t.Errorf("VisitorFuncs: path %v max depth %v", path, maxDepth)
+	}
+
+	eds, err := parse.Rewrite(&fs.Ast, &fs.Src, func(ast *parse.Ast) (string, bool) {
+		switch {
+		case ast.Nm == "Name" && ast.Src == "pt":
+			return "p", true
+		case ast.Nm == "TypeDeclEl" && ast.ChildAst(0).Src == "Shape":
+			return "Shape interface {\n\tArea() float32\n\tPerimeter() float32\n}", true
+		}
+		return "", false
+	})
+	if err != nil || len(eds) != 4 {
+		t.Errorf("Rewrite edits: %v %v", eds, err)
+	}
+	rw := parse.NewRewriter(&fs.Src)
+	rw.Edits = eds
+	var lns []string
+	for _, ln := range rw.Apply() {
+		lns = append(lns, string(ln))
+	}
+	got := strings.Join(lns, "\n")
+	txt, _ := lex.OpenFileBytes("testdata/decls.go")
+	want := strings.NewReplacer("pt", "p", "Area() float32\n}", "Area() float32\n\tPerimeter() float32\n}").Replace(string(txt))
+	if got != want {
+		t.Errorf("Rewrite result:\n%v\nwant:\n%v", got, want)
+	}
+	ast := fs.Ast.ChildAst(0).ChildAst(0)
+	if err := rw.InsertBefore(ast, "// Package decls\n"); err != nil {
+		t.Error(err)
+	}
+	if err := rw.Replace(ast, "package x"); err != nil {
+		t.Error(err)
+	}
+	if err := rw.Replace(ast.ChildAst(0), "y"); err == nil {
+		t.Errorf("expected overlapping edit error")
+	}
+	if got := string(rw.Apply()[0]) + string(rw.Apply()[1]); got != "// Package declspackage x" {
+		t.Errorf("InsertBefore and Replace: %q", got)
+	}
+
+	// a node region overlapping a prior edit is reported, not dropped silently
+	n0, n1 := fs.Ast.ChildAst(0).ChildAst(0), fs.Ast.ChildAst(0).ChildAst(1)
+	n1.SrcReg.St = n0.SrcReg.St
+	eds, err = parse.Rewrite(&fs.Ast, &fs.Src, func(ast *parse.Ast) (string, bool) {
+		return "x", ast == n0 || ast == n1
+	})
+	if err == nil || len(eds) != 1 {
+		t.Errorf("Rewrite of overlapping regions: %v %v", eds, err)
+	}
+}
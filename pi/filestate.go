@@ -46,6 +46,9 @@ type FileState struct {
 	// symbols contained within this file -- initialized at start of parsing and created by AddSymbol or PushNewScope actions.  These are then processed after parsing by the language-specific code, via Lang interface.
 	Syms syms.SymMap `json:"-" xml:"-" desc:"symbols contained within this file -- initialized at start of parsing and created by AddSymbol or PushNewScope actions.  These are then processed after parsing by the language-specific code, via Lang interface."`
 
+	// regions of this file in other (guest) languages, parsed as separate sub-files by ParseGuests
+	Guests []*Guest `json:"-" xml:"-" desc:"regions of this file in other (guest) languages, parsed as separate sub-files by ParseGuests"`
+
 	// External symbols that are entirely maintained in a language-specific way by the Lang interface code.  These are only here as a convenience and are not accessed in any way by the language-general pi code.
 	ExtSyms syms.SymMap `json:"-" xml:"-" desc:"External symbols that are entirely maintained in a language-specific way by the Lang interface code.  These are only here as a convenience and are not accessed in any way by the language-general pi code."`
 
@@ -71,6 +74,7 @@ func (fs *FileState) Init() {
 	fs.SymsMu.Lock()
 	fs.Syms = make(syms.SymMap)
 	fs.SymsMu.Unlock()
+	fs.Guests = nil
 	fs.AnonCtr = 0
 }
 
@@ -42,6 +42,9 @@ type FileStates struct {
 
 	// extra meta data associated with this FileStates
 	Meta map[string]string `desc:"extra meta data associated with this FileStates"`
+
+	// if set, returns the current source lines of another file (by absolute path) that is open for editing, e.g., in an editor or language server, with any unsaved changes, and false if it is not open -- this is used instead of the file on disk by operations over the other files of a package, such as FindReferences and Rename, and the lines must not be modified
+	OpenSrc func(fname string) ([][]rune, bool) `json:"-" xml:"-" view:"-" desc:"if set, returns the current source lines of another file (by absolute path) that is open for editing, e.g., in an editor or language server, with any unsaved changes, and false if it is not open -- this is used instead of the file on disk by operations over the other files of a package, such as FindReferences and Rename, and the lines must not be modified"`
 }
 
 // NewFileStates returns a new FileStates for given filename, basepath,
@@ -0,0 +1,147 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pi
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/goki/ki/ki"
+	"github.com/goki/ki/kit"
+	"github.com/goki/pi/lex"
+	"github.com/goki/pi/parse"
+)
+
+// FoldKinds are the different kinds of foldable regions
+type FoldKinds int
+
+//go:generate stringer -type=FoldKinds
+
+var KiT_FoldKinds = kit.Enums.AddEnum(FoldKindsN, kit.NotBitFlag, nil)
+
+func (ev FoldKinds) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(ev) }
+func (ev *FoldKinds) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(ev, b) }
+
+// FoldKinds
+const (
+	// FoldRegion is a generic region, e.g., a brace block or a section
+	FoldRegion FoldKinds = iota
+
+	// FoldComment is a run of comment lines
+	FoldComment
+
+	// FoldImports is a group of imports
+	FoldImports
+
+	FoldKindsN
+)
+
+// FoldRange is a region of the source that can be folded.  For brace
+// blocks, the region is from just after the opening brace to the
+// start of the closing brace, so the braces remain visible.
+type FoldRange struct {
+
+	// region that can be folded
+	Reg lex.Reg `desc:"region that can be folded"`
+
+	// kind of region
+	Kind FoldKinds `desc:"kind of region"`
+}
+
+// Folder is an optional interface for a Lang that computes its own folding
+// ranges (e.g., Markdown by heading level), instead of the language-general
+// FoldingRanges function
+type Folder interface {
+	// FoldingRanges returns the foldable regions of the file, sorted by starting line
+	FoldingRanges(fs *FileState) []FoldRange
+}
+
+// LangFoldingRanges returns the foldable regions of the file for given
+// language, using its Folder interface if supported, and otherwise FoldingRanges
+func LangFoldingRanges(lang Lang, fs *FileState) []FoldRange {
+	if fl, ok := lang.(Folder); ok {
+		return fl.FoldingRanges(fs)
+	}
+	return FoldingRanges(fs)
+}
+
+// FoldingRanges returns the foldable regions of the file, sorted by starting
+// line: brace blocks spanning multiple lines, matched using the nesting depth
+// from PassTwo, runs of two or more comment-only lines, and multi-line Ast
+// nodes named Import* (e.g., a Go import group).  Only the outermost region
+// is kept for each starting line, with import groups taking precedence.
+// Takes the SymsMu read lock, as ReparseLines updates the tokens and Ast under it.
+func FoldingRanges(fs *FileState) []FoldRange {
+	fs.SymsMu.RLock()
+	defer fs.SymsMu.RUnlock()
+
+	src := &fs.Src
+	byLn := make(map[int]FoldRange)
+	add := func(fr FoldRange) {
+		if fr.Reg.Ed.Ln <= fr.Reg.St.Ln {
+			return
+		}
+		if cur, has := byLn[fr.Reg.St.Ln]; has {
+			outer := fr.Reg.Ed.Ln > cur.Reg.Ed.Ln || (fr.Reg.Ed.Ln == cur.Reg.Ed.Ln && fr.Reg.St.Ch < cur.Reg.St.Ch)
+			if cur.Kind == FoldImports || (fr.Kind != FoldImports && !outer) {
+				return
+			}
+		}
+		byLn[fr.Reg.St.Ln] = fr
+	}
+
+	var open []lex.Pos // start of block at each depth
+	for ln, lxs := range src.Lexs {
+		for _, lx := range lxs {
+			tok := lx.Tok.Tok
+			d := lx.Tok.Depth
+			switch {
+			case tok.IsPunctGpLeft():
+				for len(open) <= d {
+					open = append(open, lex.PosZero)
+				}
+				open[d] = lex.Pos{Ln: ln, Ch: lx.Ed}
+			case tok.IsPunctGpRight():
+				if d < len(open) {
+					add(FoldRange{Reg: lex.Reg{St: open[d], Ed: lex.Pos{Ln: ln, Ch: lx.St}}})
+				}
+			}
+		}
+	}
+
+	cst := -1 // start of comment run
+	nln := src.NLines()
+	for ln := 0; ln <= nln; ln++ {
+		if ln < nln && ln < len(src.Comments) && len(src.Comments[ln]) > 0 && len(src.Lexs[ln]) == 0 {
+			if cst < 0 {
+				cst = ln
+			}
+			continue
+		}
+		if cst >= 0 && ln-1 > cst {
+			lst := src.Comments[ln-1]
+			add(FoldRange{Reg: lex.Reg{St: lex.Pos{Ln: cst, Ch: src.Comments[cst][0].St}, Ed: lex.Pos{Ln: ln - 1, Ch: lst[len(lst)-1].Ed}}, Kind: FoldComment})
+		}
+		cst = -1
+	}
+
+	fs.Ast.FuncDownMeFirst(0, nil, func(k ki.Ki, level int, d any) bool {
+		ast := k.(*parse.Ast)
+		if !strings.HasPrefix(ast.Nm, "Import") {
+			return ki.Continue
+		}
+		add(FoldRange{Reg: ast.SrcReg, Kind: FoldImports})
+		return ki.Break
+	})
+
+	frs := make([]FoldRange, 0, len(byLn))
+	for _, fr := range byLn {
+		frs = append(frs, fr)
+	}
+	sort.Slice(frs, func(i, j int) bool {
+		return frs[i].Reg.St.Ln < frs[j].Reg.St.Ln
+	})
+	return frs
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pi
+
+import (
+	"github.com/goki/ki/ints"
+	"github.com/goki/pi/filecat"
+	"github.com/goki/pi/lex"
+	"github.com/goki/pi/syms"
+)
+
+// Guest is a region of a host file that is in another (guest) language,
+// e.g., a fenced code block in markdown, or a script or style in html.
+// The region is lexed by the guest lexer installed by the SetGuestLex
+// lexer action, and is parsed as a separate sub-file by the Parser
+// for the guest language, in FileState.ParseGuests.
+// Positions within the guest FileState are relative to the start of
+// the region, and are mapped back to the host file by HostPos.
+type Guest struct {
+
+	// name of the guest language, as given in the host source
+	Lang string `desc:"name of the guest language, as given in the host source"`
+
+	// supported file type of the guest language
+	Sup filecat.Supported `desc:"supported file type of the guest language"`
+
+	// region of the host file in the guest language
+	Reg lex.Reg `desc:"region of the host file in the guest language"`
+
+	// state for the guest sub-file, with the source of the region, and the lexing and parsing output for it -- positions are relative to the start of Reg
+	FileState *FileState `desc:"state for the guest sub-file, with the source of the region, and the lexing and parsing output for it -- positions are relative to the start of Reg"`
+}
+
+// HostPos returns the position in the host file for given position in the guest
+func (gs *Guest) HostPos(pos lex.Pos) lex.Pos {
+	if pos.Ln == 0 {
+		pos.Ch += gs.Reg.St.Ch
+	}
+	pos.Ln += gs.Reg.St.Ln
+	return pos
+}
+
+// HostReg returns the region in the host file for given region in the guest
+func (gs *Guest) HostReg(reg lex.Reg) lex.Reg {
+	return lex.Reg{St: gs.HostPos(reg.St), Ed: gs.HostPos(reg.Ed)}
+}
+
+// GuestPos returns the position in the guest for given position in the host file,
+// which must be within Reg
+func (gs *Guest) GuestPos(pos lex.Pos) lex.Pos {
+	pos.Ln -= gs.Reg.St.Ln
+	if pos.Ln == 0 {
+		pos.Ch -= gs.Reg.St.Ch
+	}
+	return pos
+}
+
+// HostSyms maps the regions and filename of given symbols, and all their
+// children, from the guest to the host file, in place
+func (gs *Guest) HostSyms(sm syms.SymMap, fname string) {
+	for _, sy := range sm {
+		sy.Filename = fname
+		sy.Region = gs.HostReg(sy.Region)
+		sy.SelectReg = gs.HostReg(sy.SelectReg)
+		gs.HostSyms(sy.Children, fname)
+	}
+}
+
+// HostErrs adds given errors from the guest to the host error list,
+// with positions and filename mapped to the host file
+func (gs *Guest) HostErrs(host *lex.ErrorList, errs lex.ErrorList, fname string) {
+	for _, e := range errs {
+		host.Add(gs.HostPos(e.Pos), fname, e.Msg, e.Src, e.Rule)
+	}
+}
+
+// RegSrc returns a copy of the source lines for given region
+func (fs *FileState) RegSrc(reg lex.Reg) [][]rune {
+	nl := fs.Src.NLines()
+	if reg.St.Ln >= nl || reg.Ed.Ln < reg.St.Ln {
+		return nil
+	}
+	ed := reg.Ed
+	if ed.Ln >= nl {
+		ed = lex.Pos{Ln: nl - 1, Ch: len(fs.Src.Lines[nl-1])}
+	}
+	src := make([][]rune, ed.Ln-reg.St.Ln+1)
+	for ln := reg.St.Ln; ln <= ed.Ln; ln++ {
+		lr := fs.Src.Lines[ln]
+		st, end := 0, len(lr)
+		if ln == reg.St.Ln {
+			st = ints.MinInt(reg.St.Ch, end)
+		}
+		if ln == ed.Ln {
+			end = ints.MaxInt(ints.MinInt(ed.Ch, end), st)
+		}
+		src[ln-reg.St.Ln] = append([]rune(nil), lr[st:end]...)
+	}
+	return src
+}
+
+// ParseGuests parses each of the guest regions recorded by the lexer
+// in LexState.Guests with the Parser for its language, as a separate
+// sub-file in Guests.  The errors and symbols of each guest are mapped back
+// to host positions, and added to the host errors for the same stage
+// (LexState.Errs, TwoState.Errs and ParseState.Errs) and Syms.
+// Must be called after LexAll, and after ParseAll if the host has a parser,
+// as that resets the errors.  Regions in languages without a Parser are
+// skipped.  Language-specific processing of the symbols (e.g., resolving
+// types and imports in Go) is not done for guests.
+func (fs *FileState) ParseGuests() {
+	fs.Guests = nil
+	for _, gr := range fs.LexState.Guests {
+		if gr.Reg.Ed == lex.PosErr {
+			continue
+		}
+		lp, err := LangSupport.PropsByName(gr.Lang)
+		if err != nil || lp.Parser == nil {
+			continue
+		}
+		pr := lp.Parser
+		gs := &Guest{Lang: gr.Lang, Sup: lp.Sup, Reg: gr.Reg}
+		gfs := NewFileState()
+		gfs.SetSrc(fs.RegSrc(gr.Reg), fs.Src.Filename, fs.Src.BasePath, lp.Sup)
+		gs.FileState = gfs
+		fs.Guests = append(fs.Guests, gs)
+		pr.LexAll(gfs)
+		gs.HostErrs(&fs.LexState.Errs, gfs.LexState.Errs, fs.Src.Filename)
+		gs.HostErrs(&fs.TwoState.Errs, gfs.TwoState.Errs, fs.Src.Filename)
+		if !pr.Parser.HasChildren() {
+			continue
+		}
+		pr.ParseAll(gfs)
+		gs.HostErrs(&fs.ParseState.Errs, gfs.ParseState.Errs, fs.Src.Filename)
+		gs.HostSyms(gfs.ParseState.Syms, fs.Src.Filename)
+		fs.SymsMu.Lock()
+		fs.Syms.CopyFrom(gfs.ParseState.Syms, true)
+		fs.SymsMu.Unlock()
+	}
+}
+
+// GuestAt returns the guest containing given position in the host file,
+// or nil if it is not in a guest
+func (fs *FileState) GuestAt(pos lex.Pos) *Guest {
+	for _, gs := range fs.Guests {
+		if gs.Reg.Contains(pos) {
+			return gs
+		}
+	}
+	return nil
+}
@@ -68,6 +68,11 @@ type Lang interface {
 	// open and view, or direct text to show.
 	Lookup(fs *FileStates, text string, pos lex.Pos) complete.Lookup
 
+	// FindReferences returns the regions in the file, and in the other files
+	// of the loaded package, where the symbol at given position is used,
+	// including where it is defined.  Returns nil if there is no symbol there.
+	FindReferences(fs *FileStates, pos lex.Pos) []lex.FileReg
+
 	// IndentLine returns the indentation level for given line based on
 	// previous line's indentation level, and any delta change based on
 	// e.g., brackets starting or ending the previous or current line, or
@@ -109,6 +114,39 @@ type Lang interface {
 	ParseLine(fs *FileState, line int) *FileState
 }
 
+// Renamer is an optional interface for a Lang that supports renaming symbols
+type Renamer interface {
+	// PrepareRename returns the region of the name at given position, and an
+	// error if the symbol there cannot be renamed.
+	PrepareRename(fs *FileStates, pos lex.Pos) (lex.Reg, error)
+
+	// Rename returns the edits to rename the symbol at given position to newName,
+	// for every occurrence of it in the file and the other files of its package.
+	// Returns an error, and no edits, if newName would clash with an existing
+	// symbol or the symbol cannot otherwise be renamed.
+	Rename(fs *FileStates, pos lex.Pos, newName string) ([]lex.FileEdit, error)
+}
+
+// SignatureHelper is an optional interface for a Lang that supports
+// showing the signature of a function while typing its arguments
+type SignatureHelper interface {
+	// SignatureHelp returns the signature of the function being called for given
+	// text, which ends at given position within the file inside the arguments of
+	// the call, along with the index of the current argument.
+	// Returns false if the text is not in a call or the function is not found.
+	SignatureHelp(fs *FileStates, text string, pos lex.Pos) (complete.Signature, bool)
+}
+
+// Reparser is an optional interface for a Lang that supports incremental
+// re-parsing of an edited region of the file, instead of ParseFile
+type Reparser interface {
+	// ReparseLines updates the Done state of the file for an edit that replaced
+	// nold lines starting at line st with nnew lines, given the full new txt.
+	// Only the edited region is re-lexed and re-parsed when possible, and
+	// otherwise the whole file is parsed as in ParseFile.
+	ReparseLines(fs *FileStates, txt []byte, st, nold, nnew int)
+}
+
 // LangDirOpts provides options for Lang ParseDir method
 type LangDirOpts struct {
 
@@ -10,6 +10,7 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/goki/ki/ki"
@@ -36,6 +37,18 @@ type Parser struct {
 	// if true, reports errors after parsing, to stdout
 	ReportErrs bool `desc:"if true, reports errors after parsing, to stdout"`
 
+	// if true, the parser recovers from errors by skipping to the next EOS at the same depth, adding BadStmt or BadExpr Ast nodes -- see parse.State Recover
+	Recover bool `desc:"if true, the parser recovers from errors by skipping to the next EOS at the same depth, adding BadStmt or BadExpr Ast nodes -- see parse.State Recover"`
+
+	// if true, the parser uses a packrat memo table of rule match results, which bounds parsing time for pathological inputs at some cost in memory -- see parse.Memo
+	Memo bool `desc:"if true, the parser uses a packrat memo table of rule match results, which bounds parsing time for pathological inputs at some cost in memory -- see parse.Memo"`
+
+	// if true, the parser collects a profile of the rule matching, with counters for each rule, accumulated in parse.ProfileTotal -- see parse.Profile
+	Profile bool `desc:"if true, the parser collects a profile of the rule matching, with counters for each rule, accumulated in parse.ProfileTotal -- see parse.Profile"`
+
+	// issues found in the grammar by parse.Rule Analyze, in InitAll
+	GrammarIssues []parse.GrammarIssue `json:"-" xml:"-" desc:"issues found in the grammar by parse.Rule Analyze, in InitAll"`
+
 	// when loaded from file, this is the modification time of the parser -- re-processes cache if parser is newer than cached files
 	ModTime time.Time `json:"-" xml:"-" desc:"when loaded from file, this is the modification time of the parser -- re-processes cache if parser is newer than cached files"`
 }
@@ -62,6 +75,7 @@ func (pr *Parser) InitAll() {
 	pr.Lexer.Validate(&fs.LexState)
 	pr.Parser.CompileAll(&fs.ParseState)
 	pr.Parser.Validate(&fs.ParseState)
+	pr.GrammarIssues = pr.Parser.Analyze(&fs.ParseState)
 }
 
 // LexInit gets the lexer ready to start lexing
@@ -146,12 +160,14 @@ func (pr *Parser) LexLine(fs *FileState, ln int, txt []rune) lex.Line {
 	fs.LexState.SetLine(fs.Src.Lines[ln])
 	pst := fs.Src.PrevStack(ln)
 	fs.LexState.Stack = pst.Clone()
+	ng := len(fs.LexState.Guests)
 	for !fs.LexState.AtEol() {
 		mrule := pr.Lexer.LexStart(&fs.LexState)
 		if mrule == nil {
 			break
 		}
 	}
+	fs.LexState.Guests = fs.LexState.Guests[:ng] // guest regions are only from LexAll
 	initDepth := fs.Src.PrevDepth(ln)
 	pr.PassTwo.NestDepthLine(fs.LexState.Lex, initDepth)                         // important to set this one's depth
 	fs.Src.SetLine(ln, fs.LexState.Lex, fs.LexState.Comments, fs.LexState.Stack) // before saving here
@@ -181,6 +197,9 @@ func (pr *Parser) LexAll(fs *FileState) {
 	// lprf := prof.Start("LexRun") // quite fast now..
 	pr.LexRun(fs)
 	// lprf.End()
+	if nl := fs.Src.NLines(); nl > 0 { // guest still open at end of source
+		fs.LexState.EndGuest(lex.Pos{Ln: nl - 1, Ch: len(fs.Src.Lines[nl-1])})
+	}
 	pr.DoPassTwo(fs) // takes virtually no time
 }
 
@@ -188,6 +207,9 @@ func (pr *Parser) LexAll(fs *FileState) {
 func (pr *Parser) ParserInit(fs *FileState) bool {
 	fs.AnonCtr = 0
 	fs.ParseState.Init(&fs.Src, &fs.Ast)
+	fs.ParseState.Recover = pr.Recover
+	fs.ParseState.Memo.On = pr.Memo
+	fs.ParseState.Profile.On = pr.Profile
 	return true
 }
 
@@ -236,6 +258,12 @@ func (pr *Parser) ParseAll(fs *FileState) {
 	if !parse.GuiActive {
 		fs.ParseState.Ast.UpdateEnd(updt)
 	}
+	if pr.Memo {
+		parse.AddMemoTotal(&fs.ParseState.Memo.Stats)
+	}
+	if pr.Profile {
+		parse.AddProfileTotal(&fs.ParseState.Profile)
+	}
 	if pr.ReportErrs {
 		if fs.ParseHasErrs() {
 			fmt.Println(fs.ParseErrReport())
@@ -330,6 +358,105 @@ func (pr *Parser) SaveGrammar(filename string) error {
 	return ofl.Close()
 }
 
+// OpenGrammar opens lexer and parser rules from a BNF-like .pig file,
+// as written by SaveGrammar, replacing the current rules
+func (pr *Parser) OpenGrammar(filename string) error {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	return pr.ReadGrammar(b)
+}
+
+// ReadGrammar reads lexer and parser rules from bytes in the BNF-like .pig
+// format written by SaveGrammar, replacing the current rules: lexer rules come
+// first, and a line of all / characters starts the parser rules.  A comment
+// line starting with the name of the following rule sets its Desc.
+// The PassTwo settings are not in the grammar and are not changed.
+func (pr *Parser) ReadGrammar(b []byte) error {
+	pr.Lexer.DeleteChildren(ki.DestroyKids)
+	pr.Parser.DeleteChildren(ki.DestroyKids)
+	stack := []ki.Ki{pr.Lexer.This()}
+	isParser := false
+	var last *parse.Rule // last parser rule, for its Acts
+	descNm, desc := "", ""
+	for li, ln := range strings.Split(string(b), "\n") {
+		str := strings.TrimSpace(ln)
+		switch {
+		case str == "":
+			continue
+		case len(str) > 2 && strings.Trim(str, "/") == "":
+			if len(stack) > 1 {
+				return fmt.Errorf("ReadGrammar: line %d: missing } in lexer rules", li+1)
+			}
+			isParser = true
+			stack = []ki.Ki{pr.Parser.This()}
+			continue
+		case str == "}":
+			if len(stack) <= 1 {
+				return fmt.Errorf("ReadGrammar: line %d: extra }", li+1)
+			}
+			stack = stack[:len(stack)-1]
+			continue
+		case strings.HasPrefix(str, "--->Acts:"):
+			if last == nil {
+				return fmt.Errorf("ReadGrammar: line %d: Acts without a rule", li+1)
+			}
+			if err := last.Acts.FromString(strings.TrimPrefix(str, "--->Acts:")); err != nil {
+				return fmt.Errorf("ReadGrammar: line %d: %v", li+1, err)
+			}
+			continue
+		case strings.HasPrefix(str, "//") && !strings.HasPrefix(str, "// OFF: "):
+			cstr := strings.TrimPrefix(strings.TrimPrefix(str, "// "), "// OFF: ")
+			descNm, desc = cstr, ""
+			if si := strings.Index(cstr, " "); si >= 0 {
+				descNm, desc = cstr[:si], strings.TrimSuffix(cstr[si+1:], " ")
+			}
+			continue
+		}
+		off := strings.HasPrefix(str, "// OFF: ")
+		str = strings.TrimPrefix(str, "// OFF: ")
+		group := str == "{" || strings.HasSuffix(str, " {")
+		if group {
+			str = strings.TrimSpace(strings.TrimSuffix(str, "{"))
+		}
+		nm, rstr := str, ""
+		if ni := strings.IndexAny(str, ": \t"); ni >= 0 {
+			nm, rstr = str[:ni], strings.TrimSpace(str[ni:])
+			rstr = strings.TrimSpace(strings.TrimPrefix(rstr, ":"))
+		}
+		par := stack[len(stack)-1]
+		var err error
+		if isParser {
+			pri := par.AddNewChild(parse.KiT_Rule, nm).(*parse.Rule)
+			pri.Off = off
+			if nm == descNm {
+				pri.Desc = desc
+			}
+			err = pri.SetGrammar(rstr)
+			last = pri
+		} else {
+			lri := par.AddNewChild(lex.KiT_Rule, nm).(*lex.Rule)
+			lri.Off = off
+			if nm == descNm {
+				lri.Desc = desc
+			}
+			err = lri.SetGrammar(rstr)
+		}
+		if err != nil {
+			return fmt.Errorf("ReadGrammar: line %d: %v", li+1, err)
+		}
+		descNm, desc = "", ""
+		if group {
+			stack = append(stack, par.Child(par.NumChildren()-1))
+		}
+	}
+	if len(stack) > 1 {
+		return fmt.Errorf("ReadGrammar: missing } at end")
+	}
+	return nil
+}
+
 // VersionInfo returns Pi version information
 func VersionInfo() string {
 	vinfo := Version + " date: " + VersionDate + " UTC; git commit-1: " + GitCommit
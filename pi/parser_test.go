@@ -0,0 +1,567 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pi
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goki/pi/filecat"
+	"github.com/goki/pi/lex"
+	"github.com/goki/pi/parse"
+	"github.com/goki/pi/token"
+)
+
+// TestGrammarRoundTrip checks that the rules read from the grammar written
+// for each language are the same as those loaded from its JSON, field by
+// field, that writing them again gives the same grammar, and that the .pig
+// file of the language is the one written from its JSON
+func TestGrammarRoundTrip(t *testing.T) {
+	for _, fn := range []string{"../langs/golang/go.pi", "../langs/markdown/markdown.pi", "../langs/tex/tex.pi"} {
+		pr := NewParser()
+		if err := pr.OpenJSON(fn); err != nil {
+			t.Fatal(err)
+		}
+		pig := filepath.Join(t.TempDir(), filepath.Base(fn)+"g")
+		if err := pr.SaveGrammar(pig); err != nil {
+			t.Fatal(err)
+		}
+		gb, err := os.ReadFile(pig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cb, err := os.ReadFile(fn + "g"); err != nil {
+			t.Error(err)
+		} else {
+			cs := string(cb) // the file names in the header comments differ
+			cfn := strings.TrimSuffix(strings.TrimPrefix(cs[:strings.Index(cs, "\n")], "// "), " Lexer")
+			if strings.ReplaceAll(string(gb), pig, cfn) != cs {
+				t.Errorf("%v: grammar differs from the one written from %v -- regenerate it with SaveGrammar", fn+"g", fn)
+			}
+		}
+		rp := NewParser()
+		if err := rp.OpenGrammar(pig); err != nil {
+			t.Fatalf("%v: %v", fn, err)
+		}
+		sameLexRules(t, fn, &rp.Lexer, &pr.Lexer)
+		pr.InitAll()
+		rp.InitAll()
+		sameParseRules(t, fn, &rp.Parser, &pr.Parser)
+		if err := rp.SaveGrammar(pig); err != nil {
+			t.Fatal(err)
+		}
+		rb, err := os.ReadFile(pig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(rb) != string(gb) {
+			t.Errorf("%v: grammar differs after reading and writing it again", fn)
+		}
+	}
+}
+
+// lexRuleFields returns the fields of a lexer rule set from a grammar or
+// JSON, other than its children -- Until and PushState are only included
+// for the actions that use them
+func lexRuleFields(lr *lex.Rule) string {
+	until, push := "", ""
+	for _, act := range lr.Acts {
+		switch act {
+		case lex.ReadUntil:
+			until = lr.Until
+		case lex.PushState, lex.PushDelim:
+			push = lr.PushState
+		}
+	}
+	return fmt.Sprintf("%v Off: %v Desc: %q Token: %v Match: %v Pos: %v String: %q Offset: %v SizeAdj: %v Acts: %v Until: %q PushState: %q NameMap: %v", lr.Nm, lr.Off, lr.Desc, lr.Token, lr.Match, lr.Pos, lr.String, lr.Offset, lr.SizeAdj, lr.Acts, until, push, lr.NameMap)
+}
+
+// sameLexRules reports any difference between lexer rule trees:
+// the got rule read from a grammar, and the want rule loaded from JSON
+func sameLexRules(t *testing.T, fn string, got, want *lex.Rule) {
+	t.Helper()
+	if gf, wf := lexRuleFields(got), lexRuleFields(want); gf != wf {
+		t.Errorf("%v: lexer rule differs:\n got: %v\nwant: %v", fn, gf, wf)
+	}
+	if got.NumChildren() != want.NumChildren() {
+		t.Errorf("%v: lexer rule: %v has %d children, want: %d", fn, got.Nm, got.NumChildren(), want.NumChildren())
+		return
+	}
+	for i, k := range got.Kids {
+		sameLexRules(t, fn, k.(*lex.Rule), want.Kids[i].(*lex.Rule))
+	}
+}
+
+// parseRuleFields returns the fields of a compiled parser rule, other than
+// its children, including the Rules elements compiled from the Rule string
+func parseRuleFields(pr *parse.Rule) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v Off: %v Desc: %q Rule: %q StackMatch: %q Ast: %v OptTokMap: %v FirstTokMap: %v Acts: %+v Rules:", pr.Nm, pr.Off, pr.Desc, pr.Rule, pr.StackMatch, pr.Ast, pr.OptTokMap, pr.FirstTokMap, []parse.Act(pr.Acts))
+	for _, re := range pr.Rules {
+		nm := ""
+		if re.IsRule() {
+			nm = re.Rule.Nm
+		}
+		fmt.Fprintf(&b, " {%v %v StInc: %v Match: %v Opt: %v FmNext: %v}", nm, re.Tok, re.StInc, re.Match, re.Opt, re.FmNext)
+	}
+	return b.String()
+}
+
+// sameParseRules reports any difference between compiled parser rule trees:
+// the got rule read from a grammar, and the want rule loaded from JSON
+func sameParseRules(t *testing.T, fn string, got, want *parse.Rule) {
+	t.Helper()
+	if gf, wf := parseRuleFields(got), parseRuleFields(want); gf != wf {
+		t.Errorf("%v: parser rule differs:\n got: %v\nwant: %v", fn, gf, wf)
+	}
+	if got.NumChildren() != want.NumChildren() {
+		t.Errorf("%v: parser rule: %v has %d children, want: %d", fn, got.Nm, got.NumChildren(), want.NumChildren())
+		return
+	}
+	for i, k := range got.Kids {
+		sameParseRules(t, fn, k.(*parse.Rule), want.Kids[i].(*parse.Rule))
+	}
+}
+
+func TestGrammarParse(t *testing.T) {
+	pr := NewParser()
+	if err := pr.OpenJSON("../langs/golang/go.pi"); err != nil {
+		t.Fatal(err)
+	}
+	pig := filepath.Join(t.TempDir(), "go.pig")
+	if err := pr.SaveGrammar(pig); err != nil {
+		t.Fatal(err)
+	}
+	rp := NewParser()
+	rp.PassTwo = pr.PassTwo // not in the grammar
+	if err := rp.OpenGrammar(pig); err != nil {
+		t.Fatal(err)
+	}
+	rp.InitAll()
+	src := "package main\n\nfunc main() {\n\tx := 1\n}\n"
+	fs := rp.ParseString(src, "main.go", 0)
+	if fs == nil || !fs.ParseState.Ast.HasChildren() {
+		t.Fatalf("no Ast from parser read from grammar")
+	}
+	if fs.LexHasErrs() || fs.ParseHasErrs() {
+		t.Errorf("parser read from grammar has errors:\n%v\n%v", fs.LexErrReport(), fs.ParseErrReport())
+	}
+}
+
+// analyzePig is a test parser grammar with one of each kind of GrammarIssues
+var analyzePig = `
+File {
+    Stmt:  Expr 'EOS'  >Ast
+}
+Expr {
+    KwExpr:		 opts: FirstTokMap;  {
+        Func:   'key:func' 'Name'  >Ast
+        Func2:  'key:func' '('  >Ast
+    }
+    Call:   'Name' '(' ')'  >Ast
+    Again:  'Name' '(' ')'  >Ast
+    LeftA:  LeftB  
+    Lit:    'Name'  >Ast
+    --->Acts:{ -1:ChgToken:"Nope":NameVar; }
+    Index:  'Name' '[' ']'  >Ast
+}
+LeftB:   LeftA  
+Unused:  'Name' 'EOS'  >Ast
+`
+
+func TestGrammarAnalyze(t *testing.T) {
+	gb, err := os.ReadFile("../langs/golang/go.pig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := string(gb)
+	gs = gs[:strings.Index(gs, "\n////")+1] + "///////////////////////////////////////////////////\n" + analyzePig
+	pr := NewParser()
+	if err := pr.ReadGrammar([]byte(gs)); err != nil {
+		t.Fatal(err)
+	}
+	pr.InitAll()
+	want := map[string]parse.GrammarIssues{
+		"Again":  parse.ShadowedRule,
+		"Func2":  parse.ShadowedRule,
+		"Index":  parse.ShadowedRule,
+		"LeftA":  parse.LeftRecursion,
+		"Lit":    parse.BadActPath,
+		"Unused": parse.UnreachableRule,
+	}
+	if len(pr.GrammarIssues) != len(want) {
+		t.Errorf("wrong number of grammar issues: %d, want: %d:\n%v", len(pr.GrammarIssues), len(want), pr.GrammarIssues)
+	}
+	for _, gi := range pr.GrammarIssues {
+		if k, has := want[gi.Rule.Name()]; !has || k != gi.Kind {
+			t.Errorf("unexpected grammar issue: %v", gi)
+		}
+	}
+
+	pr = NewParser()
+	if err := pr.OpenJSON("../langs/golang/go.pi"); err != nil {
+		t.Fatal(err)
+	}
+	pr.InitAll()
+	want = map[string]parse.GrammarIssues{
+		"RecvType":     parse.UnreachableRule,
+		"TypeDecl":     parse.UnreachableRule,
+		"ConstDecl":    parse.UnreachableRule,
+		"VarDecl":      parse.UnreachableRule,
+		"ForRangeOnly": parse.BadActPath,
+	}
+	if len(pr.GrammarIssues) != len(want) {
+		t.Errorf("wrong number of Go grammar issues: %d, want: %d:\n%v", len(pr.GrammarIssues), len(want), pr.GrammarIssues)
+	}
+	for _, gi := range pr.GrammarIssues {
+		if k, has := want[gi.Rule.Name()]; !has || k != gi.Kind {
+			t.Errorf("unexpected Go grammar issue: %v", gi)
+		}
+	}
+}
+
+// regexpPig is a test lexer grammar with Regexp rules
+var regexpPig = `
+SkipWhite:  TextWhitespace  if WhiteSpace  do: Next; 
+HexColor:   LitNumHex       if Regexp == "#[0-9a-fA-F]{6}\b"  do: Next; 
+Date:       LitStr          if Regexp == "\d{4}-\d{2}-\d{2}"  do: Next; 
+Lifetime:   NameLabel       if Regexp == "'[a-z_]\w*"  do: Next; 
+Other:      Text            if AnyRune  do: Next; 
+`
+
+func TestLexRegexp(t *testing.T) {
+	pr := NewParser()
+	if err := pr.ReadGrammar([]byte(regexpPig)); err != nil {
+		t.Fatal(err)
+	}
+	pr.InitAll()
+	fs := NewFileState()
+	fs.Src.InitFromString("#ff00aA 2024-01-31 &'a #ff00aa0 ü'ß\n", "test.txt", filecat.NoSupport)
+	pr.LexAll(fs)
+	var got []string
+	for _, lx := range fs.Src.Lexs[0] {
+		if lx.Tok.Tok != token.TextWhitespace {
+			got = append(got, fmt.Sprintf("%v:%d-%d", lx.Tok.Tok, lx.St, lx.Ed))
+		}
+	}
+	want := "LitNumHex:0-7 LitStr:8-18 Text:19-20 NameLabel:20-22 Text:23-31 Text:32-35" // adjacent Text is merged
+	if gs := strings.Join(got, " "); gs != want {
+		t.Errorf("wrong tokens:\n%v\nwant:\n%v", gs, want)
+	}
+
+	var gb bytes.Buffer
+	pr.Lexer.WriteGrammar(&gb, 0)
+	if !strings.Contains(gb.String(), `if Regexp == "#[0-9a-fA-F]{6}\b"`) {
+		t.Errorf("Regexp not written in grammar:\n%v", gb.String())
+	}
+	rp := NewParser()
+	if err := rp.ReadGrammar(gb.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if lr := rp.Lexer.ChildByName("Date", 0).(*lex.Rule); lr.Match != lex.Regexp || lr.String != `\d{4}-\d{2}-\d{2}` {
+		t.Errorf("Regexp rule read from grammar: %v %v", lr.Match, lr.String)
+	}
+
+	bad := NewParser()
+	if err := bad.ReadGrammar([]byte("Bad:  Text  if Regexp == \"[a-\"  do: Next;\n")); err != nil {
+		t.Fatal(err)
+	}
+	bfs := NewFileState()
+	if bad.Lexer.AsLexRule().Kids[0].(*lex.Rule).Compile(&bfs.LexState) {
+		t.Errorf("invalid Regexp compiled")
+	}
+}
+
+// indentPig is a test lexer grammar for an indentation-sensitive language
+var indentPig = `
+SkipWhite:  TextWhitespace  if WhiteSpace  do: Next; 
+Name:       Name            if Letter      do: Name; 
+Number:     LitNum          if Digit       do: Number; 
+Colon:      PunctSepColon   if String == ":"  do: Next; 
+LParen:     PunctGpLParen   if String == "("  do: Next; 
+RParen:     PunctGpRParen   if String == ")"  do: Next; 
+Comma:      PunctSepComma   if String == ","  do: Next; 
+Assign:     OpAsgnAssign    if String == "="  do: Next; 
+`
+
+// indentParsePig is a test parser grammar using the IndentBlock and
+// DedentBlock tokens to scope blocks, for the indentPig lexer
+var indentParsePig = `
+///////////////////////////////////////////////////
+File {
+    Stmts:  Stmt 'EOS'  
+}
+Stmt {
+    IfStmt:    'Name' ':' 'IndentBlock' ?BlockList 'DedentBlock'  >Ast
+    AsgnStmt:  'Name' '=' 'LitNum'  >Ast
+    ExprStmt:  'Name'  >Ast
+}
+StmtList:   Stmt 'EOS' ?StmtList  
+BlockList:  StmtList  >Ast
+`
+
+// lexIndent lexes and parses given source with the indentPig lexer and Indent,
+// and given parser grammar, returning the tokens of each line, with their depth
+func lexIndent(t *testing.T, parser, src string) (string, *FileState) {
+	pr := NewParser()
+	if err := pr.ReadGrammar([]byte(indentPig + parser)); err != nil {
+		t.Fatal(err)
+	}
+	pr.PassTwo.DoEos = true
+	pr.PassTwo.Eol = true
+	pr.PassTwo.Indent = true
+	pr.PassTwo.TabSize = 4
+	pr.InitAll()
+	fs := NewFileState()
+	fs.Src.InitFromString(src, "test.txt", filecat.NoSupport)
+	pr.LexAll(fs)
+	if parser != "" {
+		pr.ParseAll(fs)
+	}
+	var lns []string
+	for _, lxs := range fs.Src.Lexs {
+		var toks []string
+		for _, lx := range lxs {
+			toks = append(toks, fmt.Sprintf("%v:%d", lx.Tok.Tok, lx.Tok.Depth))
+		}
+		lns = append(lns, strings.Join(toks, " "))
+	}
+	return strings.Join(lns, "\n"), fs
+}
+
+func TestPassTwoIndent(t *testing.T) {
+	src := "a:\n  b = 1\n\n  c(1,\n2):\n\t  d\n  e\nf:\n  g:\n    h\n"
+	want := `Name:0 PunctSepColon:0 IndentBlock:0
+Name:1 OpAsgnAssign:1 LitNumInteger:1 EOS:1
+
+Name:1 PunctGpLParen:1 LitNumInteger:2 PunctSepComma:2
+LitNumInteger:2 PunctGpRParen:1 PunctSepColon:1 IndentBlock:1
+Name:2 EOS:2
+DedentBlock:1 EOS:1 Name:1 EOS:1
+DedentBlock:0 EOS:0 Name:0 PunctSepColon:0 IndentBlock:0
+Name:1 PunctSepColon:1 IndentBlock:1
+Name:2 EOS:2 DedentBlock:1 EOS:1 DedentBlock:0 EOS:0
+`
+	got, fs := lexIndent(t, "", src)
+	if got != want {
+		t.Errorf("wrong Indent tokens:\n%v\nwant:\n%v", got, want)
+	}
+	if len(fs.TwoState.Errs) > 0 {
+		t.Errorf("unexpected errors: %v", fs.TwoState.Errs.Report(0, "", true, true))
+	}
+	for ln, eps := range fs.Src.EosPos {
+		for _, ch := range eps {
+			if tk := fs.Src.Lexs[ln][ch].Tok.Tok; tk != token.EOS {
+				t.Errorf("line %d: EosPos %d is not an EOS: %v", ln, ch, tk)
+			}
+		}
+	}
+
+	_, fs = lexIndent(t, "", "a:\n    b\n  c\n")
+	if len(fs.TwoState.Errs) != 1 {
+		t.Errorf("expected unindent error, got: %v", fs.TwoState.Errs)
+	}
+
+	_, fs = lexIndent(t, indentParsePig, "a:\n  b = 1\n  c:\n    d\n  e\nf\n")
+	var sx bytes.Buffer
+	fs.Ast.ChildAst(0).WriteSexp(&sx)
+	want = `(File (0 0 0 0) (0 0 0 0) ""
+	(IfStmt (0 0 5 1) (0 0 5 0) "a:|>  b = 1|>  c:|>    d|>  e|>"
+		(BlockList (1 0 5 0) (1 2 4 3) "b = 1|>  c:|>    d|>  e"
+			(AsgnStmt (1 0 1 3) (1 2 1 7) "b = 1")
+			(IfStmt (2 0 4 1) (2 2 4 2) "c:|>    d|>  "
+				(BlockList (3 0 4 0) (3 4 3 5) "d"
+					(ExprStmt (3 0 3 1) (3 4 3 5) "d")))
+			(ExprStmt (4 2 4 3) (4 2 4 3) "e")))
+	(ExprStmt (5 2 5 3) (5 0 5 1) "f"))
+`
+	if sx.String() != want {
+		t.Errorf("wrong Ast for indented blocks:\n%v\nwant:\n%v", sx.String(), want)
+	}
+}
+
+// delimPig is a test lexer grammar with strings ended by delimiters from the source
+var delimPig = `
+InHeredoc:  LitStrHeredoc  if CurState == "Heredoc" {
+    EndHeredoc:  LitStrHeredoc  if @StartOfLine:Delim == ""  do: PopState; Next; 
+    Heredoc:     LitStrHeredoc  if AnyRune  do: Next; 
+}
+InRaw:  LitStr  if CurState == "Raw" {
+    EndRaw:  LitStr  if Delim == ""$1"  do: PopState; Next; 
+    Raw:     LitStr  if AnyRune  do: Next; 
+}
+InLong:  LitStr  if CurState == "Long" {
+    EndLong:  LitStr  if Delim == "]$1]"  do: PopState; Next; 
+    Long:     LitStr  if AnyRune  do: Next; 
+}
+StartHeredoc:  LitStrHeredoc  if Regexp == "<<(\w+)"  do: PushDelim: Heredoc; Next; 
+StartRaw:      LitStr         if Regexp == "r(#*)\""  do: PushDelim: Raw; Next; 
+StartLong:     LitStr         if Regexp == "\[(=*)\["  do: PushDelim: Long; Next; 
+SkipWhite:     TextWhitespace  if WhiteSpace  do: Next; 
+Name:          Name            if Letter      do: Name; 
+Other:         Text            if AnyRune     do: Next; 
+`
+
+func TestLexDelim(t *testing.T) {
+	pr := NewParser()
+	if err := pr.ReadGrammar([]byte(delimPig)); err != nil {
+		t.Fatal(err)
+	}
+	pr.InitAll()
+	if len(pr.GrammarIssues) > 0 {
+		t.Fatal(pr.GrammarIssues)
+	}
+	src := "a <<EOF\nb \"# EOF\n\nEOF\nr##\"x\"#y\n\"## z\n[==[ ]] ]=] ]==] w\n"
+	fs := NewFileState()
+	fs.Src.InitFromString(src, "test.txt", filecat.NoSupport)
+	pr.LexAll(fs)
+	lexs := func(ln int, lxs lex.Line) string {
+		var toks []string
+		for _, lx := range lxs {
+			if lx.Tok.Tok != token.TextWhitespace {
+				toks = append(toks, fmt.Sprintf("%v:%s", lx.Tok.Tok, string(fs.Src.Lines[ln][lx.St:lx.Ed])))
+			}
+		}
+		return strings.Join(toks, " ")
+	}
+	want := []string{
+		"Name:a LitStrHeredoc:<<EOF",
+		`LitStrHeredoc:b "# EOF`,
+		"",
+		"LitStrHeredoc:EOF",
+		`LitStr:r##"x"#y`,
+		`LitStr:"## Name:z`,
+		"LitStr:[==[ ]] ]=] ]==] Name:w",
+	}
+	for ln, w := range want {
+		if got := lexs(ln, fs.Src.Lexs[ln]); got != w {
+			t.Errorf("line %d: got: %v want: %v", ln, got, w)
+		}
+	}
+	if st := fs.Src.LastStacks[2]; len(st) != 1 || st[0] != "Heredoc"+lex.DelimSep+"EOF" {
+		t.Errorf("delimiter not saved in LastStacks: %q", st)
+	}
+	// line-at-time lexing uses the stack from the prior line
+	if got := lexs(1, pr.LexLine(fs, 1, fs.Src.Lines[1])); got != want[1] {
+		t.Errorf("LexLine: got: %v want: %v", got, want[1])
+	}
+
+	var gb bytes.Buffer
+	pr.Lexer.WriteGrammar(&gb, 0)
+	for _, s := range []string{`if @StartOfLine:Delim == ""`, `if Delim == ""$1"`, `do: PushDelim: Raw; Next;`} {
+		if !strings.Contains(gb.String(), s) {
+			t.Errorf("grammar does not contain: %v:\n%v", s, gb.String())
+		}
+	}
+	rp := NewParser()
+	if err := rp.ReadGrammar(gb.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if lr := rp.Lexer.ChildByName("StartRaw", 0).(*lex.Rule); len(lr.Acts) != 2 || lr.Acts[0] != lex.PushDelim || lr.PushState != "Raw" {
+		t.Errorf("PushDelim rule read from grammar: %v %v", lr.Acts, lr.PushState)
+	}
+}
+
+func TestScanner(t *testing.T) {
+	pr := NewParser()
+	if err := pr.ReadGrammar([]byte(delimPig)); err != nil {
+		t.Fatal(err)
+	}
+	pr.InitAll()
+	long := "r\"" + strings.Repeat("x", 10000) + "\" y"
+	src := "a <<EOF\nb \"# EOF\n\nEOF\nr##\"x\"#y\n\"## z\n[==[ ]] ]=] ]==] w\n" + long + "\nlast"
+	fs := NewFileState()
+	fs.Src.InitFromString(src, "test.txt", filecat.NoSupport)
+	pr.LexAll(fs)
+	var want []string
+	for ln := range fs.Src.Lines {
+		for _, lx := range fs.Src.Lexs[ln] {
+			want = append(want, fmt.Sprintf("%d:%v:%d:%d", ln, lx.Tok.Tok, lx.St, lx.Ed))
+		}
+	}
+
+	var got []string
+	sc := lex.NewScanner(&pr.Lexer, strings.NewReader(src), "test.txt")
+	err := sc.Scan(func(ln int, src []rune, lx lex.Lex) bool {
+		got = append(got, fmt.Sprintf("%d:%v:%d:%d", ln, lx.Tok.Tok, lx.St, lx.Ed))
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.NErrs > 0 {
+		t.Errorf("%d lexing errors", sc.NErrs)
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("scanner tokens differ from LexAll:\n got: %v\nwant: %v", got, want)
+	}
+
+	n := 0
+	sc = lex.NewScanner(&pr.Lexer, strings.NewReader(src), "test.txt")
+	sc.Scan(func(ln int, src []rune, lx lex.Lex) bool {
+		n++
+		return ln < 1
+	})
+	if n != 3 {
+		t.Errorf("scan did not stop at first token on line 1: %d tokens", n)
+	}
+	if st := sc.State.Stack; len(st) != 1 || st[0] != "Heredoc"+lex.DelimSep+"EOF" {
+		t.Errorf("state stack not carried over lines: %q", st)
+	}
+	// errors are passed to ErrFunc and not kept
+	pr = NewParser()
+	if err := pr.ReadGrammar([]byte("SkipWhite:  TextWhitespace  if WhiteSpace  do: Next; \nName:  Name  if Letter  do: Name; \n")); err != nil {
+		t.Fatal(err)
+	}
+	pr.InitAll()
+	var errs []string
+	sc = lex.NewScanner(&pr.Lexer, strings.NewReader("ab 12\ncd\n3"), "test.txt")
+	sc.ErrFunc = func(e *lex.Error) {
+		errs = append(errs, e.Pos.String())
+	}
+	sc.Scan(func(ln int, src []rune, lx lex.Lex) bool { return true })
+	if strings.Join(errs, " ") != "1:3 3" || sc.NErrs != 2 || len(sc.State.Errs) != 1 {
+		t.Errorf("scanner errors: %v %d %d", errs, sc.NErrs, len(sc.State.Errs))
+	}
+}
+
+// TestLexConcurrent lexes files at the same time with the same lexer, which
+// must keep all per-match state in the lex.State -- run with -race
+func TestLexConcurrent(t *testing.T) {
+	for _, tst := range []struct{ pig, src string }{
+		{regexpPig, strings.Repeat("#ff00aA 2024-01-31 &'a #ff00aa0 ü'ß\n", 50)},
+		{delimPig, strings.Repeat("a <<EOF\nb \"# EOF\nEOF\nr##\"x\"#y\n\"## z\n[==[ ]] ]=] ]==] w\n", 50)},
+	} {
+		pr := NewParser()
+		if err := pr.ReadGrammar([]byte(tst.pig)); err != nil {
+			t.Fatal(err)
+		}
+		pr.InitAll()
+		lexs := func() string {
+			fs := NewFileState()
+			fs.Src.InitFromString(tst.src, "test.txt", filecat.NoSupport)
+			pr.LexAll(fs)
+			var b strings.Builder
+			for _, lxs := range fs.Src.Lexs {
+				b.WriteString(lxs.String() + "\n")
+			}
+			return b.String()
+		}
+		want := lexs()
+		res := make(chan string)
+		for i := 0; i < 4; i++ {
+			go func() { res <- lexs() }()
+		}
+		for i := 0; i < 4; i++ {
+			if got := <-res; got != want {
+				t.Errorf("concurrent lexing differs:\n%v\nwant:\n%v", got, want)
+			}
+		}
+	}
+}
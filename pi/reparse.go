@@ -0,0 +1,329 @@
+// Copyright (c) 2018, The GoKi Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pi
+
+import (
+	"github.com/goki/ki/ki"
+	"github.com/goki/pi/lex"
+	"github.com/goki/pi/parse"
+	"github.com/goki/pi/syms"
+)
+
+// ReparseLines updates the lexing and parsing of the file state after an
+// edit that replaced nold lines starting at line st with nnew lines, which
+// must already be in fs.Src.Lines.  Only the edited lines are re-lexed
+// (continuing while the lexer stack at the end of a line differs from before,
+// e.g., for an opened comment), and pass two is re-run on them starting from
+// the depth of the preceding token.  The top-level Ast nodes overlapping the
+// re-lexed lines are then re-parsed, until the parser is back in sync at the
+// start of a following node, and the new nodes and their symbols replace the
+// old ones, with the positions of everything after the edit shifted.
+// If the edit changes the nesting depth after it, involves the first
+// top-level node (e.g., the Go package), or there is no prior parse, the
+// entire file is lexed and parsed again, as it also is with the PassTwo
+// Indent option, where the depth depends on the indentation of all prior
+// lines.  Returns true if done incrementally.
+// The SymsMu lock is held throughout, as the symbols and Ast are changed in
+// place, and may still be in use, e.g., by type resolution after a prior edit.
+func (pr *Parser) ReparseLines(fs *FileState, st, nold, nnew int) bool {
+	fs.SymsMu.Lock()
+	defer fs.SymsMu.Unlock()
+	src := &fs.Src
+	ps := &fs.ParseState
+	nlines := src.NLines()
+	if st < 0 || nold < 0 || nnew < 0 || st+nnew > nlines || len(src.Lexs) != nlines-nnew+nold || ps.Src != src || len(fs.TwoState.Errs) > 0 || !fs.Ast.HasChildren() || pr.PassTwo.Indent {
+		pr.ReparseAll(fs)
+		return false
+	}
+	file := fs.Ast.ChildAst(0)
+	if !file.HasChildren() {
+		pr.ReparseAll(fs)
+		return false
+	}
+
+	// stack at the end of the old edited lines, to check against the new one
+	var oldEdStack lex.Stack
+	if nold > 0 {
+		oldEdStack = src.LastStacks[st+nold-1].Clone()
+	} else {
+		pst := src.PrevStack(st)
+		oldEdStack = pst.Clone()
+	}
+
+	nk := file.NumChildren()
+	eti := 0 // nodes before this start in the edited lines, and are re-parsed
+	for eti < nk && file.ChildAst(eti).TokReg.St.Ln < st+nold {
+		eti++
+	}
+
+	delta := nnew - nold
+	if delta != 0 {
+		ShiftLines(fs, st+nold, delta)
+	}
+	if delta > 0 {
+		src.LinesInserted(st, delta)
+	} else if delta < 0 {
+		src.LinesDeleted(st, st-delta)
+	}
+
+	// re-lex, starting at previous line with tokens, which can get an EOS from this one
+	lst := st
+	if pp, ok := src.PrevTokenPos(lex.Pos{Ln: st}); ok {
+		lst = pp.Ln
+	}
+	nlexErr := len(fs.LexState.Errs)
+	ned := st + nnew // end of re-lexed lines
+	for ln := lst; ln < ned; ln++ {
+		pr.RelexLine(fs, ln)
+	}
+	if !stacksEqual(src.PrevStack(ned), oldEdStack) {
+		for ned < nlines {
+			old := src.LastStacks[ned].Clone()
+			pr.RelexLine(fs, ned)
+			ned++
+			if stacksEqual(src.LastStacks[ned-1], old) {
+				break
+			}
+		}
+	}
+
+	// top-level nodes overlapping the re-lexed lines are re-parsed, and all of
+	// their lines must be re-lexed to undo token changes from the prior parse
+	fi := 0
+	for fi < nk && lastLine(file.ChildAst(fi)) < lst {
+		fi++
+	}
+	if fi == 0 {
+		pr.ReparseAll(fs)
+		return false
+	}
+	rst := lst // start of lines to re-lex and re-parse
+	if fi < nk && file.ChildAst(fi).TokReg.St.Ln < rst {
+		rst = file.ChildAst(fi).TokReg.St.Ln
+	}
+	red := ned // end of lines to re-lex and re-parse
+	ti := fi   // start of unchanged tail nodes
+	for ti < nk && (ti < eti || file.ChildAst(ti).TokReg.St.Ln < red) {
+		if ll := lastLine(file.ChildAst(ti)) + 1; ll > red {
+			red = ll
+		}
+		ti++
+	}
+	for ln := rst; ln < lst; ln++ {
+		pr.RelexLine(fs, ln)
+	}
+	for ln := ned; ln < red && ln < nlines; ln++ {
+		pr.RelexLine(fs, ln)
+	}
+	fs.LexState.Errs = append(filterErrs(fs.LexState.Errs[:nlexErr], rst, red), fs.LexState.Errs[nlexErr:]...)
+
+	// pass two on the re-lexed lines, which must leave the following depth as it was
+	for ln := rst; ln < red && ln < nlines; ln++ {
+		pr.PassTwo.NestDepthLine(src.Lexs[ln], src.PrevDepth(ln))
+		src.EosPos[ln] = nil
+	}
+	if pr.PassTwo.DoEos {
+		fs.TwoState.SetSrc(src)
+		pr.PassTwo.EosDetectPos(&fs.TwoState, lex.Pos{Ln: rst}, red-rst)
+	}
+	if np, ok := src.ValidTokenPos(lex.Pos{Ln: red}); ok {
+		lx := src.LexAt(np)
+		depth := lx.Tok.Depth
+		if lx.Tok.Tok.IsPunctGpRight() {
+			depth++
+		}
+		if depth != src.PrevDepth(np.Ln) {
+			pr.ReparseAll(fs)
+			return false
+		}
+	} else if src.PrevDepth(nlines) != 0 {
+		pr.ReparseAll(fs)
+		return false
+	}
+
+	sp, ok := src.ValidTokenPos(lex.Pos{Ln: rst})
+	if !ok {
+		sp = lex.Pos{Ln: nlines}
+	}
+	var tail, removed []*parse.Ast
+	for i := fi; i < nk; i++ {
+		ast := file.ChildAst(i)
+		if i >= ti {
+			tail = append(tail, ast)
+		} else {
+			removed = append(removed, ast)
+		}
+	}
+	old := make(map[ki.Ki]bool) // old nodes, for removing their symbols
+	for _, ast := range removed {
+		ast.FuncDownMeFirst(0, nil, func(k ki.Ki, level int, d any) bool {
+			old[k] = true
+			return ki.Continue
+		})
+	}
+	updt := file.UpdateStart()
+	for i := fi + len(removed) - 1; i >= fi; i-- {
+		file.DeleteChildAtIndex(i, ki.DestroyKids)
+	}
+	ps.AllocRules()
+	ps.Stack.Reset()
+	ps.Pos = sp
+	nparseErr := len(ps.Errs)
+	for !ps.AtEof() {
+		if len(tail) > 0 && !ps.Pos.IsLess(tail[0].TokReg.St) {
+			break
+		}
+		pr.Parser.StartParse(ps)
+		if ps.AtEofNext() {
+			break
+		}
+	}
+	if len(tail) > 0 && tail[0].TokReg.St != ps.Pos { // parsed into the tail, with its changed tokens
+		file.UpdateEnd(updt)
+		pr.ReparseAll(fs)
+		return false
+	}
+	nnk := file.NumChildren() - fi - len(tail) // new nodes, added at the end
+	for i := 0; i < nnk; i++ {
+		file.Kids.Move(fi+len(tail)+i, fi+i)
+	}
+	edLn := nlines
+	if len(tail) > 0 {
+		edLn = tail[0].TokReg.St.Ln
+	}
+	ps.Errs = append(filterErrs(ps.Errs[:nparseErr], sp.Ln, edLn), ps.Errs[nparseErr:]...)
+	file.UpdateEnd(updt)
+
+	removeOldSyms(ps.Syms, old, make(map[*syms.Symbol]bool))
+	return true
+}
+
+// ReparseAll lexes and parses the entire file again, as the fallback
+// for ReparseLines
+func (pr *Parser) ReparseAll(fs *FileState) {
+	fs.Src.AllocLines()
+	pr.LexAll(fs)
+	pr.ParseAll(fs)
+}
+
+// RelexLine lexes given line again, using the lexer stack at the end of the
+// previous line, and saves the results in the source -- nesting depth and
+// EOS are not set, as they are done by PassTwo
+func (pr *Parser) RelexLine(fs *FileState, ln int) {
+	fs.LexState.Ln = ln
+	fs.LexState.SetLine(fs.Src.Lines[ln])
+	pst := fs.Src.PrevStack(ln)
+	fs.LexState.Stack = pst.Clone()
+	for !fs.LexState.AtEol() {
+		if mrule := pr.Lexer.LexStart(&fs.LexState); mrule == nil {
+			break
+		}
+	}
+	fs.Src.SetLine(ln, fs.LexState.Lex, fs.LexState.Comments, fs.LexState.Stack)
+}
+
+// ShiftLines shifts the line numbers of all positions in the Ast, symbols
+// from this file, and errors, that are at or after given line, by delta
+// lines, after lines have been inserted (delta > 0) or deleted (delta < 0).
+// Must be called under SymsMu lock.
+func ShiftLines(fs *FileState, ln, delta int) {
+	fs.Ast.FuncDownMeFirst(0, nil, func(k ki.Ki, level int, d any) bool {
+		ast := k.(*parse.Ast)
+		shiftReg(&ast.TokReg, ln, delta)
+		shiftReg(&ast.SrcReg, ln, delta)
+		return ki.Continue
+	})
+	shiftSyms(fs.ParseState.Syms, fs.Src.Filename, ln, delta, make(map[*syms.Symbol]bool))
+	for _, el := range []lex.ErrorList{fs.LexState.Errs, fs.ParseState.Errs} {
+		for _, er := range el {
+			if er.Pos.Ln >= ln {
+				er.Pos.Ln += delta
+			}
+		}
+	}
+}
+
+// lastLine returns the last line with tokens of given Ast node
+func lastLine(ast *parse.Ast) int {
+	if ast.TokReg.Ed.Ch == 0 && ast.TokReg.Ed.Ln > ast.TokReg.St.Ln {
+		return ast.TokReg.Ed.Ln - 1
+	}
+	return ast.TokReg.Ed.Ln
+}
+
+// shiftReg shifts the line of the region start and end by delta if at or after ln
+func shiftReg(reg *lex.Reg, ln, delta int) {
+	if reg.St.Ln >= ln {
+		reg.St.Ln += delta
+	}
+	if reg.Ed.Ln >= ln {
+		reg.Ed.Ln += delta
+	}
+}
+
+// shiftSyms shifts the regions of symbols and types from given file, recursively
+func shiftSyms(sm syms.SymMap, fname string, ln, delta int, done map[*syms.Symbol]bool) {
+	for _, sy := range sm {
+		if done[sy] {
+			continue
+		}
+		done[sy] = true
+		if sy.Filename == fname {
+			shiftReg(&sy.Region, ln, delta)
+			shiftReg(&sy.SelectReg, ln, delta)
+		}
+		for _, ty := range sy.Types {
+			if ty.Filename == fname {
+				shiftReg(&ty.Region, ln, delta)
+			}
+		}
+		shiftSyms(sy.Children, fname, ln, delta, done)
+	}
+}
+
+// removeOldSyms removes the symbols and types created by the given old Ast
+// nodes, which were not re-created by parsing again, recursively
+func removeOldSyms(sm syms.SymMap, old map[ki.Ki]bool, done map[*syms.Symbol]bool) {
+	for nm, sy := range sm {
+		if sy.Ast != nil && old[sy.Ast] {
+			delete(sm, nm)
+			continue
+		}
+		if done[sy] {
+			continue
+		}
+		done[sy] = true
+		for tnm, ty := range sy.Types {
+			if ty.Ast != nil && old[ty.Ast] {
+				delete(sy.Types, tnm)
+			}
+		}
+		removeOldSyms(sy.Children, old, done)
+	}
+}
+
+// filterErrs returns the errors not within lines [st, ed)
+func filterErrs(el lex.ErrorList, st, ed int) lex.ErrorList {
+	var nel lex.ErrorList
+	for _, er := range el {
+		if er.Pos.Ln < st || er.Pos.Ln >= ed {
+			nel = append(nel, er)
+		}
+	}
+	return nel
+}
+
+// stacksEqual returns true if the two lexer stacks are the same
+func stacksEqual(a, b lex.Stack) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
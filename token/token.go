@@ -518,6 +518,16 @@ const (
 	TextStyleUnderline
 	TextStyleLink
 
+	// IndentBlock is the start of an indented block, inserted by PassTwo
+	// for indentation-sensitive languages (python) -- like a left brace,
+	// the tokens after it are one nesting level deeper.  It is at the end
+	// (in the Text category) so the values of the other tokens are unchanged.
+	IndentBlock
+
+	// DedentBlock is the end of an indented block, inserted by PassTwo
+	// for each level closed by a less indented line -- like a right brace
+	DedentBlock
+
 	TokensN
 )
 
@@ -812,4 +822,7 @@ var Names = map[Tokens]string{
 	TextStyleTraceback:  "gt",
 	TextStyleUnderline:  "gl",
 	TextStyleLink:       "ga",
+
+	IndentBlock: "Indent",
+	DedentBlock: "Dedent",
 }
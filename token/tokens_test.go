@@ -46,3 +46,19 @@ func TestCats(t *testing.T) {
 	CatItem(t, TextSpellErr, Text, Text)
 	CatItem(t, TextStylePrompt, Text, TextStyle)
 }
+
+// TestValues checks that tokens added later do not change the values of
+// existing tokens, which can be saved or serialized
+func TestValues(t *testing.T) {
+	vals := map[Tokens]int{Background: 5, Keyword: 6, Name: 13, PunctGpLBrace: 140, TextStyleLink: 176, IndentBlock: 177, DedentBlock: 178}
+	for tk, v := range vals {
+		if int(tk) != v {
+			t.Errorf("%v value: %d, want: %d", tk, int(tk), v)
+		}
+		var ft Tokens
+		if err := ft.FromString(tk.String()); err != nil || ft != tk {
+			t.Errorf("%v from string: %v %v", tk, ft, err)
+		}
+	}
+	CatItem(t, IndentBlock, Text, TextStyle)
+}